@@ -1,9 +1,9 @@
 package accesscontrolapp
 
 import (
+	"crypto/ed25519"
 	"crypto/sha256"
 	_ "crypto/sha256"
-	"dare_randomized_access_control/cointoss"
 	"encoding/binary"
 	"fmt"
 	"github.com/cloudflare/circl/group"
@@ -13,6 +13,7 @@ import (
 	"github.com/petar/GoLLRB/llrb"
 	"github.com/samber/lo"
 	"log/slog"
+	"math"
 	"math/rand"
 	"unsafe"
 )
@@ -22,14 +23,28 @@ import (
 var LogMembershipChanges = true
 
 type Msg struct {
+	// Id of the post operation creating the message. Control messages have no id.
+	Id      uuid.UUID
 	Issuer  uuid.UUID
 	Content string
+	// Edits made to the message, in the order they were applied. Content holds the text of the last one.
+	Edits   []Edit
+	Deleted bool
+	// Parent is the id of the message this one replies to. Messages that are not replies have no parent.
+	Parent uuid.UUID
+	// Channel the message was posted in. Messages posted to the whole group have no channel.
+	Channel uuid.UUID
 }
 
 type User struct {
 	Id         uuid.UUID
 	Points     *llrb.LLRB
 	prettyName string
+	pubKey     ed25519.PublicKey
+	shareKey   group.Element
+	role       Role
+	// roleChange is the role change that set the role of the user. Nil if they hold the role they joined with.
+	roleChange *roleChange
 }
 
 type pt struct {
@@ -47,6 +62,32 @@ type App struct {
 	users      map[uuid.UUID]*User
 	Msgs       []Msg
 	graphNodes map[uuid.UUID]*backnode
+	// msgIdx maps the ids of the posts to their position in Msgs
+	msgIdx    map[uuid.UUID]int
+	reactions map[uuid.UUID]reactions
+	channels  map[uuid.UUID]*Channel
+	proposals map[uuid.UUID]*removalProposal
+	bans      map[uuid.UUID]*ban
+	refreshes map[uuid.UUID]*refresh
+	// refreshOrder holds the ids of the refreshes, in the order they were opened
+	refreshOrder []uuid.UUID
+	// deals holds the ids of the deals that qualified, in the order they were executed
+	deals []uuid.UUID
+	// pendingCoins maps the seeds of the coin tosses lacking values to the deals they combine
+	pendingCoins map[int64][]uuid.UUID
+	// coinShares holds the coin share operations of the operations being executed, by seed
+	coinShares map[int64][]*Op
+	observers  []Observer
+	// rejections reports the operations rejected, in the order they were executed
+	rejections []RejectedOp
+	// applied counts the operations executed, the last of which had idx lastIdx
+	applied int
+	lastIdx int64
+	// digest chains the hashes of the ids of the operations executed
+	digest []byte
+	// signed is set when the group was created with a public key. Every user must then sign their operations, as
+	// anyone could issue operations on behalf of the users without a key.
+	signed bool
 }
 
 const (
@@ -67,49 +108,147 @@ func ExecuteCRDT(crdt *CRDT, numPoints, threshold int) (*App, error) {
 
 func ExecuteOpList(opList []*Op, numPoints int, threshold int) (*App, error) {
 	app := NewApp(numPoints, threshold)
+	return app, app.execute(opList, math.MaxInt64)
+}
+
+// Execute executes the operations, which must follow in the total order those executed before.
+func (app *App) Execute(opList []*Op) error {
+	return app.execute(opList, math.MaxInt64)
+}
+
+// execute applies the operations with idx up to until, along with those resolved together with the last of them.
+func (app *App) execute(opList []*Op, until int64) error {
+	app.collectCoinShares(opList)
 	i := 0
-	for i < len(opList) {
+	for i < len(opList) && opList[i].idx <= until {
+		start := i
 		op := opList[i]
 		switch op.kind {
 		case Init:
 			err := app.init(op)
 			if err != nil {
-				return app, err
+				return err
 			}
 			i++
 		case Add:
 			err := app.add(op)
 			if err != nil {
 				slog.Warn("Unable to compute add operation", "err", err, "idx", op.idx, "op", op.content.(*AddOp))
+				app.rejected(op, err)
 			}
 			i++
 		case Rem:
-			if isConcurrent(opList, i) {
-				err := app.concurrentRem(op, opList[i+1], op.idx)
-				if err != nil {
-					slog.Warn("Unable to compute concurrent removal operation", "err", err, "idx", op.idx, "op", op.content.(*RemOp))
-					i++
-				} else {
-					i += 2
-				}
-			} else {
-				err := app.rem(op)
-				if err != nil {
-					slog.Warn("Unable to compute removal operation", "err", err, "idx", op.idx, "op", op.content.(*RemOp))
-				}
-				i++
+			concurrent := concurrentRems(opList, i)
+			app.remConcurrent(concurrent)
+			i += len(concurrent)
+		case Leave:
+			err := app.leave(op)
+			if err != nil {
+				slog.Warn("Unable to compute leave operation", "err", err, "idx", op.idx, "op", op.content.(*LeaveOp))
+				app.rejected(op, err)
+			}
+			i++
+		case Transfer:
+			err := app.transfer(op)
+			if err != nil {
+				slog.Warn("Unable to compute transfer operation", "err", err, "idx", op.idx, "op", op.content.(*TransferOp))
+				app.rejected(op, err)
+			}
+			i++
+		case GrantRole, RevokeRole:
+			concurrent := concurrentRoleChanges(opList, i)
+			app.changeRolesConcurrent(concurrent)
+			i += len(concurrent)
+		case ProposeRemoval:
+			err := app.proposeRemoval(op)
+			if err != nil {
+				slog.Warn("Unable to compute removal proposal", "err", err, "idx", op.idx, "op", op.content.(*ProposeRemovalOp))
+				app.rejected(op, err)
 			}
+			i++
+		case Vote:
+			err := app.vote(op)
+			if err != nil {
+				slog.Warn("Unable to compute vote", "err", err, "idx", op.idx, "op", op.content.(*VoteOp))
+				app.rejected(op, err)
+			}
+			i++
+		case Deal:
+			err := app.deal(op)
+			if err != nil {
+				slog.Warn("Unable to compute deal operation", "err", err, "idx", op.idx)
+				app.rejected(op, err)
+			}
+			i++
+		case CoinShare:
+			err := app.revealCoinShares(op)
+			if err != nil {
+				slog.Warn("Unable to compute coin share operation", "err", err, "idx", op.idx)
+				app.rejected(op, err)
+			}
+			i++
+		case Unban:
+			err := app.unban(op)
+			if err != nil {
+				slog.Warn("Unable to compute unban operation", "err", err, "idx", op.idx, "op", op.content.(*UnbanOp))
+				app.rejected(op, err)
+			}
+			i++
+		case CreateChannel:
+			err := app.createChannel(op)
+			if err != nil {
+				slog.Warn("Unable to compute create channel operation", "err", err, "idx", op.idx, "op", op.content.(*CreateChannelOp))
+				app.rejected(op, err)
+			}
+			i++
+		case AddToChannel:
+			err := app.addToChannel(op)
+			if err != nil {
+				slog.Warn("Unable to compute add to channel operation", "err", err, "idx", op.idx, "op", op.content.(*AddToChannelOp))
+				app.rejected(op, err)
+			}
+			i++
 		case Post:
 			err := app.post(op)
 			if err != nil {
 				slog.Warn("Unable to compute post operation", "err", err, "idx", op.idx, "op", op.content.(*PostOp))
+				app.rejected(op, err)
+			}
+			i++
+		case Reply:
+			err := app.reply(op)
+			if err != nil {
+				slog.Warn("Unable to compute reply operation", "err", err, "idx", op.idx, "op", op.content.(*ReplyOp))
+				app.rejected(op, err)
+			}
+			i++
+		case React:
+			err := app.react(op)
+			if err != nil {
+				slog.Warn("Unable to compute react operation", "err", err, "idx", op.idx, "op", op.content.(*ReactOp))
+				app.rejected(op, err)
+			}
+			i++
+		case EditPost:
+			err := app.editPost(op)
+			if err != nil {
+				slog.Warn("Unable to compute edit operation", "err", err, "idx", op.idx, "op", op.content.(*EditPostOp))
+				app.rejected(op, err)
+			}
+			i++
+		case DeletePost:
+			err := app.deletePost(op)
+			if err != nil {
+				slog.Warn("Unable to compute delete operation", "err", err, "idx", op.idx, "op", op.content.(*DeletePostOp))
+				app.rejected(op, err)
 			}
 			i++
 		default:
-			return app, fmt.Errorf("unhandled operation type")
+			return fmt.Errorf("unhandled operation type")
 		}
+		app.record(opList[start:i])
 	}
-	return app, nil
+	return nil
 }
 
 func NewApp(numPoints, threshold int) *App {
@@ -119,12 +258,21 @@ func NewApp(numPoints, threshold int) *App {
 		Value: group.Ristretto255.RandomScalar(r),
 	}
 	return &App{
-		secret:     share,
-		numPoints:  numPoints,
-		threshold:  threshold,
-		users:      make(map[uuid.UUID]*User),
-		Msgs:       make([]Msg, 0),
-		graphNodes: make(map[uuid.UUID]*backnode),
+		secret:       share,
+		numPoints:    numPoints,
+		threshold:    threshold,
+		users:        make(map[uuid.UUID]*User),
+		Msgs:         make([]Msg, 0),
+		graphNodes:   make(map[uuid.UUID]*backnode),
+		msgIdx:       make(map[uuid.UUID]int),
+		reactions:    make(map[uuid.UUID]reactions),
+		channels:     make(map[uuid.UUID]*Channel),
+		proposals:    make(map[uuid.UUID]*removalProposal),
+		bans:         make(map[uuid.UUID]*ban),
+		refreshes:    make(map[uuid.UUID]*refresh),
+		refreshOrder: make([]uuid.UUID, 0),
+		deals:        make([]uuid.UUID, 0),
+		pendingCoins: make(map[int64][]uuid.UUID),
 	}
 }
 
@@ -134,30 +282,34 @@ func (app *App) init(op *Op) error {
 	}
 	pts := llrb.New()
 	init := op.content.(*InitOp)
+	if valid, reason := app.hasValidSignature(op, init.pubKey); !valid {
+		return reason
+	}
+	app.signed = len(init.pubKey) != 0
 	for _, p := range lo.Range(app.numPoints) {
 		pts.InsertNoReplace(&pt{pt: p})
 	}
+	bnode := app.initialBacknode(op.id)
 	points := lo.Map(lo.Range(app.numPoints), func(p int, _ int) uint { return uint(p) })
 	user := newUser(init.initial, init.prettyName, points)
-	bnode := app.initialBacknode(op.id, init.initial, app.numPoints)
-	app.graphNodes[bnode.id] = bnode
+	user.role = RoleOwner
+	user.pubKey = init.pubKey
+	user.shareKey = init.shareKey
 	app.users[init.initial] = user
+	app.graphNodes[bnode.id] = bnode
+	app.openRefresh(op.id)
+	app.emit(MemberAdded{Op: op.id, Issuer: init.initial, Added: init.initial, Points: app.numPoints})
 	if LogMembershipChanges {
 		app.Msgs = append(app.Msgs, Msg{Issuer: init.initial, Content: createControlMsgf(cyan, "%s created group with %d points", user.prettyName, app.numPoints)})
 	}
 	return nil
 }
 
-func (app *App) initialBacknode(id, owner uuid.UUID, points int) *backnode {
-	shares := cointoss.ShareRandomSecret(uint(app.threshold), uint(points))
-	ot := lo.Map(shares, func(_ secretsharing.Share, i int) *ownerTransfer {
-		return &ownerTransfer{shareIdx: uint(i), owner: owner}
-	})
+func (app *App) initialBacknode(id uuid.UUID) *backnode {
 	return &backnode{
-		id:             id,
-		deltaVals:      shares,
-		ownerTransfers: ot,
-		prev:           []*backnode{},
+		id:        id,
+		deltaVals: []secretsharing.Share{},
+		prev:      []*backnode{},
 	}
 }
 
@@ -165,56 +317,73 @@ func (app *App) add(op *Op) error {
 	add := op.content.(*AddOp)
 	if canAdd, reason := app.canAdd(op); !canAdd {
 		app.graphNodes[op.id] = app.dummyBNode(op)
-		return fmt.Errorf(reason)
+		return reason
 	}
+	bnode := app.addBnode(op)
 	issuer := app.users[add.issuer]
 	for _, p := range add.points {
 		issuer.Points.Delete(&pt{pt: int(p)})
 	}
 	added := newUser(add.added, add.prettyName, add.points)
+	added.pubKey = add.pubKey
+	added.shareKey = add.shareKey
 	app.users[add.added] = added
-	app.graphNodes[op.id] = app.addBnode(op, add)
+	app.graphNodes[op.id] = bnode
+	app.openRefresh(op.id)
 	slog.Debug("Added user", "issuer", add.issuer, "added", add.added, "points", len(add.points))
+	app.emit(MemberAdded{Op: op.id, Issuer: add.issuer, Added: add.added, Points: len(add.points)})
 	if LogMembershipChanges {
 		app.Msgs = append(app.Msgs, Msg{Issuer: add.issuer, Content: createControlMsgf(cyan, "%s added %s with %d points", issuer.prettyName, added.prettyName, len(add.points))})
 	}
 	return nil
 }
 
-func (app *App) canAdd(op *Op) (bool, string) {
+func (app *App) canAdd(op *Op) (bool, *Rejection) {
 	if !app.hasPrevious(op) {
-		return false, "previous operation ids do not exist"
+		return false, reject(ReasonMissingPrevious, "previous operation ids do not exist")
 	} else if len(op.prevIds) == 0 {
-		return false, "add operation must have at least one previous operation"
+		return false, reject(ReasonNoPrevious, "add operation must have at least one previous operation")
 	}
 	add := op.content.(*AddOp)
 	if add.issuer == add.added {
-		return false, "user cannot add themselves"
+		return false, reject(ReasonSelfTarget, "user cannot add themselves")
 	} else if len(add.points) == 0 {
-		return false, "at least a single point must be given"
+		return false, reject(ReasonInvalidPoints, "at least a single point must be given")
 	}
 	issuer := app.users[add.issuer]
 	if issuer == nil {
-		return false, "operation issuer is not a user"
-	} else if len(add.points) >= issuer.Points.Len() {
-		return false, "issuer cannot give more or equal points than what they have"
-	} else if !lo.EveryBy(add.points, func(p uint) bool { return issuer.Points.Has(&pt{pt: int(p)}) }) {
-		return false, "issuer cannot give points they do not have"
+		return false, reject(ReasonNotAMember, "operation issuer is not a user")
+	} else if valid, reason := app.hasValidSignature(op, issuer.pubKey); !valid {
+		return false, reason
+	} else if !issuer.canWrite() {
+		return false, reject(ReasonReadOnly, "read-only users cannot add users")
+	} else if canGive, reason := canGivePoints(issuer.Points, add.points); !canGive {
+		return false, reason
 	}
 	if app.users[add.added] != nil {
-		return false, "added user already exists"
+		return false, reject(ReasonAlreadyMember, "added user already exists")
+	} else if removedBy, banned := app.BannedBy(add.added); banned {
+		return false, reject(ReasonBanned, "added user was banned by operation %s", removedBy).causedBy(removedBy)
 	}
-	return true, ""
+	return true, nil
 }
 
-func (app *App) addBnode(op *Op, add *AddOp) *backnode {
-	ot := lo.Map(add.points, func(p uint, _ int) *ownerTransfer { return &ownerTransfer{shareIdx: p, owner: add.added} })
+// canGivePoints checks that the issuer owns the points and keeps at least one point after giving them away.
+func canGivePoints(owned *llrb.LLRB, points []uint) (bool, *Rejection) {
+	if len(points) >= owned.Len() {
+		return false, reject(ReasonInvalidPoints, "issuer cannot give more or equal points than what they have")
+	} else if !lo.EveryBy(points, func(p uint) bool { return owned.Has(&pt{pt: int(p)}) }) {
+		return false, reject(ReasonInvalidPoints, "issuer cannot give points they do not have")
+	}
+	return true, nil
+}
+
+func (app *App) addBnode(op *Op) *backnode {
 	prev := lo.Map(op.prevIds, func(id uuid.UUID, _ int) *backnode { return app.graphNodes[id] })
 	return &backnode{
-		id:             op.id,
-		deltaVals:      cointoss.ShareRandomSecret(uint(app.threshold), uint(app.numPoints)),
-		ownerTransfers: ot,
-		prev:           prev,
+		id:        op.id,
+		deltaVals: []secretsharing.Share{},
+		prev:      prev,
 	}
 }
 
@@ -223,81 +392,47 @@ func (app *App) post(op *Op) error {
 	poster := app.users[post.poster]
 	app.graphNodes[op.id] = app.postBNode(op)
 	if !app.hasPrevious(op) {
-		return fmt.Errorf("previous operation ids do not exist")
+		return reject(ReasonMissingPrevious, "previous operation ids do not exist")
 	} else if len(op.prevIds) == 0 {
-		return fmt.Errorf("post operation must have at least one previous operation")
+		return reject(ReasonNoPrevious, "post operation must have at least one previous operation")
 	} else if poster == nil {
-		return fmt.Errorf("operation poster is not a user")
+		return reject(ReasonNotAMember, "operation poster is not a user")
+	} else if valid, reason := app.hasValidSignature(op, poster.pubKey); !valid {
+		return reason
+	} else if !poster.canWrite() {
+		return reject(ReasonReadOnly, "read-only users cannot post")
+	} else if !app.canAccess(post.poster, post.channel) {
+		return reject(ReasonNotInChannel, "poster is not a member of the channel")
 	}
 	msg := Msg{
+		Id:      op.id,
 		Issuer:  post.poster,
 		Content: post.msg,
+		Channel: post.channel,
 	}
+	app.msgIdx[op.id] = len(app.Msgs)
 	app.Msgs = append(app.Msgs, msg)
 	slog.Debug("Posted message", "poster", post.poster, "msg", post.msg)
+	app.emit(MessagePosted{Msg: msg})
 	return nil
 }
 
 func (app *App) postBNode(op *Op) *backnode {
 	prev := lo.Map(op.prevIds, func(id uuid.UUID, _ int) *backnode { return app.graphNodes[id] })
 	bnode := &backnode{
-		id:             op.id,
-		deltaVals:      []secretsharing.Share{},
-		ownerTransfers: []*ownerTransfer{},
-		prev:           prev,
+		id:        op.id,
+		deltaVals: []secretsharing.Share{},
+		prev:      prev,
 	}
 	return bnode
 }
 
-func isConcurrent(opList []*Op, i int) bool {
-	assert.Equal(Rem, opList[i].kind, "First operation must be removal when this method is called")
-	if (i+1) >= len(opList) || opList[i+1].kind != Rem {
-		return false
-	}
-	rem1 := opList[i].content.(*RemOp)
-	rem2 := opList[i+1].content.(*RemOp)
-	return rem1.issuer == rem2.removed && rem1.removed == rem2.issuer
-}
-
-func (app *App) concurrentRem(op1, op2 *Op, seed int64) error {
-	canRem, reason := app.canRemUser(op1)
-	if !canRem {
-		app.graphNodes[op1.id] = app.dummyBNode(op1)
-		return fmt.Errorf(reason)
-	} else if !app.hasPrevious(op2) {
-		if err := app.rem(op1); err != nil {
-			return err
-		}
-		app.graphNodes[op2.id] = app.dummyBNode(op2)
-		return nil
-	}
-	allPrev := lo.Map(append(op1.prevIds, op2.prevIds...), func(id uuid.UUID, _ int) *backnode { return app.graphNodes[id] })
-	coin, err := computeCoinToss(seed, allPrev)
-	if err != nil {
-		return fmt.Errorf("unable to compute coin toss: %v", err)
-	}
-	threshold := app.computeThreshold(op1)
-	if coin < threshold {
-		if err = app.rem(op1); err != nil {
-			return err
-		}
-		app.graphNodes[op2.id] = app.dummyBNode(op2)
-	} else {
-		if err = app.rem(op2); err != nil {
-			return err
-		}
-		app.graphNodes[op1.id] = app.dummyBNode(op1)
-	}
-	return nil
-}
-
 func (app *App) dummyBNode(op *Op) *backnode {
 	prev := lo.Map(op.prevIds, func(id uuid.UUID, _ int) *backnode { return app.graphNodes[id] })
 	return &backnode{
-		id:             op.id,
-		deltaVals:      []secretsharing.Share{},
-		ownerTransfers: []*ownerTransfer{},
-		prev:           prev,
+		id:        op.id,
+		deltaVals: []secretsharing.Share{},
+		prev:      prev,
 	}
 }
 
@@ -306,79 +441,55 @@ func (app *App) rem(op *Op) error {
 	canRem, reason := app.canRemUser(op)
 	if !canRem {
 		app.graphNodes[op.id] = app.dummyBNode(op)
-		return fmt.Errorf(reason)
+		return reason
 	}
+	bnode := app.remBNode(op)
 	issuer := app.users[rem.issuer]
 	removed := app.users[rem.removed]
 	assert.True(areSetsDisjoint(issuer.Points, removed.Points), "points must be disjoint")
 	transferPoints(removed.Points, issuer.Points)
-	app.graphNodes[op.id] = app.remBNode(op)
-	delete(app.users, rem.removed)
+	app.deleteUser(rem.removed)
+	app.ban(removed, op.id)
+	app.graphNodes[op.id] = bnode
+	app.openRefresh(op.id)
 	slog.Debug("Removed user", "issuer", rem.issuer, "removed", rem.removed)
+	app.emit(MemberRemoved{Op: op.id, Kind: Rem, Issuer: rem.issuer, Removed: rem.removed})
 	if LogMembershipChanges {
 		app.Msgs = append(app.Msgs, Msg{Issuer: rem.issuer, Content: createControlMsgf(red, "%s removed %s", issuer.prettyName, removed.prettyName)})
 	}
 	return nil
 }
 
-func (app *App) canRemUser(op *Op) (bool, string) {
+func (app *App) canRemUser(op *Op) (bool, *Rejection) {
 	rem := op.content.(*RemOp)
 	if !app.hasPrevious(op) {
-		return false, "previous operation ids do not exist"
+		return false, reject(ReasonMissingPrevious, "previous operation ids do not exist")
 	} else if len(op.prevIds) == 0 {
-		return false, "removal operation must have at least one previous operation"
+		return false, reject(ReasonNoPrevious, "removal operation must have at least one previous operation")
 	} else if rem.issuer == rem.removed {
-		return false, "user cannot remove themselves"
+		return false, reject(ReasonSelfTarget, "user cannot remove themselves")
 	}
 	issuer := app.users[rem.issuer]
 	removed := app.users[rem.removed]
 	if issuer == nil {
-		return false, "operation issuer is not a user"
+		return false, reject(ReasonNotAMember, "operation issuer is not a user")
 	} else if removed == nil {
-		return false, "removed user is not in the system"
+		return false, reject(ReasonUnknownUser, "removed user is not in the system")
+	} else if valid, reason := app.hasValidSignature(op, issuer.pubKey); !valid {
+		return false, reason
+	} else if !issuer.canWrite() {
+		return false, reject(ReasonReadOnly, "read-only users cannot remove users")
 	}
-	return true, ""
+	return true, nil
 }
 
 func (app *App) remBNode(op *Op) *backnode {
 	prev := lo.Map(op.prevIds, func(id uuid.UUID, _ int) *backnode { return app.graphNodes[id] })
-	rem := op.content.(*RemOp)
-	ot := make([]*ownerTransfer, 0)
-	removed := app.users[rem.removed]
-	removed.Points.AscendGreaterOrEqual(removed.Points.Min(), func(val llrb.Item) bool {
-		ot = append(ot, &ownerTransfer{shareIdx: uint(val.(*pt).pt), owner: rem.issuer})
-		return true
-	})
 	return &backnode{
-		id:             op.id,
-		deltaVals:      cointoss.ShareRandomSecret(uint(app.threshold), uint(app.numPoints)),
-		ownerTransfers: ot,
-		prev:           prev,
-	}
-}
-
-func (app *App) computeThreshold(op *Op) float64 {
-	rem := op.content.(*RemOp)
-	issuer := app.users[rem.issuer]
-	removed := app.users[rem.removed]
-	issuerPoints := issuer.Points.Len()
-	totalPoints := issuerPoints + removed.Points.Len()
-	threshold := float64(issuerPoints) / float64(totalPoints)
-	return threshold
-}
-
-func computeCoinToss(seed int64, prev []*backnode) (float64, error) {
-	shares := getCurrentShares(prev)
-	base := getECBase(seed)
-	pointShares := lo.Map(shares, func(s secretsharing.Share, _ int) cointoss.PointShare {
-		return cointoss.ShareToPoint(s, base)
-	})
-	secret := cointoss.RecoverSecretFromPoints(pointShares)
-	coin, err := cointoss.HashPointToDouble(secret)
-	if err != nil {
-		return 0, fmt.Errorf("unable to hash secret point to number: %v", err)
+		id:        op.id,
+		deltaVals: []secretsharing.Share{},
+		prev:      prev,
 	}
-	return coin, nil
 }
 
 func getECBase(seed int64) group.Element {
@@ -409,15 +520,20 @@ func areSetsDisjoint(a, b *llrb.LLRB) bool {
 }
 
 func newUser(id uuid.UUID, name string, points []uint) *User {
-	pts := llrb.New()
-	for _, p := range points {
-		pts.InsertNoReplace(&pt{pt: int(p)})
-	}
 	return &User{
 		Id:         id,
-		Points:     pts,
+		Points:     newPointSet(points),
 		prettyName: name,
+		role:       RoleMember,
+	}
+}
+
+func newPointSet(points []uint) *llrb.LLRB {
+	pts := llrb.New()
+	for _, p := range points {
+		pts.InsertNoReplace(&pt{pt: int(p)})
 	}
+	return pts
 }
 
 func (app *App) hasPrevious(op *Op) bool {
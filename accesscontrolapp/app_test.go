@@ -352,14 +352,98 @@ func TestShouldRemoveConflictingConcurrently(t *testing.T) {
 	assert.NoError(t, err)
 	firstNode := hashgraph.NewNode(crdt.Init(firstId, ""), nil)
 	addNode := hashgraph.NewNode(crdt.Add(firstId, secondId, "", makePtRange(0, 1)), []*hashgraph.OpNode{firstNode})
-	hashgraph.NewNode(crdt.Rem(secondId, firstId), []*hashgraph.OpNode{addNode})
-	hashgraph.NewNode(crdt.Rem(firstId, secondId), []*hashgraph.OpNode{addNode})
+	deal := committeeDealNode(&crdt, []uuid.UUID{firstId, secondId}, addNode, NewApp(points, 2))
+	hashgraph.NewNode(crdt.Rem(secondId, firstId), []*hashgraph.OpNode{deal})
+	hashgraph.NewNode(crdt.Rem(firstId, secondId), []*hashgraph.OpNode{deal})
 	hashgraph.RunHashgraph(0, firstNode)
 	app, err := ExecuteCRDT(&crdt, points, 2)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(app.users))
 }
 
+func TestShouldResolveEvenConflictingRemovalsEitherWay(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	wins := make(map[bool]int)
+	for i := 0; i < 40; i++ {
+		crdt := NewCRDT()
+		ids := genIds(2, r)
+		firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+		addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 50)), []*hashgraph.OpNode{firstNode})
+		deal := committeeDealNode(&crdt, ids, addNode, NewApp(100, 2))
+		hashgraph.NewNode(crdt.Rem(ids[1], ids[0]), []*hashgraph.OpNode{deal})
+		hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{deal})
+		hashgraph.RunHashgraph(0, firstNode)
+		app, err := ExecuteCRDT(&crdt, 100, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(app.users))
+		wins[app.users[ids[0]] != nil]++
+	}
+	assert.Greater(t, wins[true], 0)
+	assert.Greater(t, wins[false], 0)
+}
+
+func TestShouldResolveRemovalCycleWithSingleDraw(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	removals := make(map[int]int)
+	for i := 0; i < 30; i++ {
+		crdt := NewCRDT()
+		ids := genIds(3, r)
+		firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+		add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 33)), []*hashgraph.OpNode{firstNode})
+		add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(33, 66)), []*hashgraph.OpNode{add1Node})
+		deal := committeeDealNode(&crdt, ids, add2Node, NewApp(99, 2))
+		for j := range ids {
+			hashgraph.NewNode(crdt.Rem(ids[j], ids[(j+1)%len(ids)]), []*hashgraph.OpNode{deal})
+		}
+		hashgraph.RunHashgraph(0, firstNode)
+		app, err := ExecuteCRDT(&crdt, 99, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(app.users))
+		removed, _ := lo.Find(lo.Range(len(ids)), func(j int) bool { return app.users[ids[j]] == nil })
+		remover := (removed + len(ids) - 1) % len(ids)
+		assert.Equal(t, 66, app.users[ids[remover]].Points.Len())
+		assert.Equal(t, 2, len(app.Rejected()))
+		again, err := ExecuteCRDT(&crdt, 99, 2)
+		assert.NoError(t, err)
+		assert.Nil(t, again.users[ids[removed]])
+		removals[removed]++
+	}
+	assert.Equal(t, 3, len(removals))
+}
+
+func TestShouldResolveRemovalCycleByPoints(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	trials := 100
+	removals := make([]int, 3)
+	for i := 0; i < trials; i++ {
+		crdt := NewCRDT()
+		ids := genIds(3, r)
+		firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+		add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(10, 40)), []*hashgraph.OpNode{firstNode})
+		add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(40, 100)), []*hashgraph.OpNode{add1Node})
+		deal := committeeDealNode(&crdt, ids, add2Node, NewApp(100, 2))
+		for j := range ids {
+			hashgraph.NewNode(crdt.Rem(ids[j], ids[(j+1)%len(ids)]), []*hashgraph.OpNode{deal})
+		}
+		hashgraph.RunHashgraph(0, firstNode)
+		app, err := ExecuteCRDT(&crdt, 100, 2)
+		assert.NoError(t, err)
+		for j := range ids {
+			if app.users[ids[j]] == nil {
+				removals[j]++
+			}
+		}
+	}
+	// The user removed is the target of the first issuer drawn, who holds 10, 30 and 60 of the 100 points.
+	assert.Equal(t, trials, lo.Sum(removals))
+	assert.InDelta(t, 60, removals[0], 15)
+	assert.InDelta(t, 10, removals[1], 10)
+	assert.InDelta(t, 30, removals[2], 15)
+}
+
 func TestShouldRemoveLowerDepthFirst(t *testing.T) {
 	LogMembershipChanges = false
 	r := rand.New(rand.NewSource(int64(0)))
@@ -401,12 +485,13 @@ func TestShouldHandleThreeWayConcurrentRemovals(t *testing.T) {
 	add1Node := hashgraph.NewNode(crdt.Add(firstId, secondId, "", makePtRange(0, points/3)), []*hashgraph.OpNode{firstNode})
 	add2Node := hashgraph.NewNode(crdt.Add(firstId, thirdId, "", makePtRange(points/3, 2*points/3)), []*hashgraph.OpNode{add1Node})
 	add3Node := hashgraph.NewNode(crdt.Add(firstId, watcherId, "", []uint{uint(points - 1)}), []*hashgraph.OpNode{add2Node})
-	remAB := hashgraph.NewNode(crdt.Rem(firstId, secondId), []*hashgraph.OpNode{add3Node})
-	remBA := hashgraph.NewNode(crdt.Rem(secondId, firstId), []*hashgraph.OpNode{add3Node})
-	remBC := hashgraph.NewNode(crdt.Rem(secondId, thirdId), []*hashgraph.OpNode{add3Node})
-	remCB := hashgraph.NewNode(crdt.Rem(thirdId, secondId), []*hashgraph.OpNode{add3Node})
-	remAC := hashgraph.NewNode(crdt.Rem(firstId, thirdId), []*hashgraph.OpNode{add3Node})
-	remCA := hashgraph.NewNode(crdt.Rem(thirdId, firstId), []*hashgraph.OpNode{add3Node})
+	deal := committeeDealNode(&crdt, []uuid.UUID{firstId, secondId, thirdId}, add3Node, NewApp(points, 2))
+	remAB := hashgraph.NewNode(crdt.Rem(firstId, secondId), []*hashgraph.OpNode{deal})
+	remBA := hashgraph.NewNode(crdt.Rem(secondId, firstId), []*hashgraph.OpNode{deal})
+	remBC := hashgraph.NewNode(crdt.Rem(secondId, thirdId), []*hashgraph.OpNode{deal})
+	remCB := hashgraph.NewNode(crdt.Rem(thirdId, secondId), []*hashgraph.OpNode{deal})
+	remAC := hashgraph.NewNode(crdt.Rem(firstId, thirdId), []*hashgraph.OpNode{deal})
+	remCA := hashgraph.NewNode(crdt.Rem(thirdId, firstId), []*hashgraph.OpNode{deal})
 	hashgraph.NewNode(crdt.Post(watcherId, "placeholder"), []*hashgraph.OpNode{remAB, remBA, remBC, remCB, remAC, remCA})
 	hashgraph.RunHashgraph(0, firstNode)
 	app, err := ExecuteCRDT(&crdt, points, 2)
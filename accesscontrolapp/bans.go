@@ -0,0 +1,95 @@
+package accesscontrolapp
+
+import (
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"log/slog"
+	"math/big"
+	"slices"
+)
+
+// Users removed from the group, whether by another member or by vote, are banned and cannot be added again.
+// Members lift a ban by approving it with unban operations. Approvals are weighted by the points of the approvers when
+// they are counted, after every approval, and the ban is lifted once the approvers still in the group hold more than
+// UnbanQuorum of the points held by all members. Users leaving the group are not banned.
+
+// UnbanQuorum is the fraction of the points held by all members that the approvers of an unban must exceed.
+var UnbanQuorum = big.NewRat(1, 2)
+
+type ban struct {
+	// removedBy is the id of the operation that removed the user
+	removedBy  uuid.UUID
+	prettyName string
+	approvals  map[uuid.UUID]bool
+}
+
+// BannedBy returns the id of the operation that removed the user, if they are banned.
+func (app *App) BannedBy(user uuid.UUID) (uuid.UUID, bool) {
+	if b := app.bans[user]; b != nil {
+		return b.removedBy, true
+	}
+	return uuid.Nil, false
+}
+
+// Banned returns the ids of the banned users, sorted.
+func (app *App) Banned() []uuid.UUID {
+	banned := lo.Keys(app.bans)
+	slices.SortFunc(banned, compareIds)
+	return banned
+}
+
+func (app *App) ban(user *User, removedBy uuid.UUID) {
+	app.bans[user.Id] = &ban{
+		removedBy:  removedBy,
+		prettyName: user.prettyName,
+		approvals:  make(map[uuid.UUID]bool),
+	}
+	slog.Debug("Banned user", "user", user.Id, "removedBy", removedBy)
+}
+
+func (app *App) unban(op *Op) error {
+	app.graphNodes[op.id] = app.dummyBNode(op)
+	unban := op.content.(*UnbanOp)
+	if canUnban, reason := app.canUnban(op); !canUnban {
+		return reason
+	}
+	b := app.bans[unban.user]
+	b.approvals[unban.issuer] = true
+	approvers := lo.FilterMap(lo.Keys(b.approvals), func(id uuid.UUID, _ int) (*User, bool) {
+		user := app.users[id]
+		return user, user != nil
+	})
+	approvedPoints := lo.SumBy(approvers, func(u *User) int { return u.Points.Len() })
+	totalPoints := lo.SumBy(lo.Values(app.users), func(u *User) int { return u.Points.Len() })
+	required := new(big.Rat).Mul(UnbanQuorum, big.NewRat(int64(totalPoints), 1))
+	slog.Debug("Approved unban", "issuer", unban.issuer, "user", unban.user, "approvedPoints", approvedPoints)
+	if big.NewRat(int64(approvedPoints), 1).Cmp(required) <= 0 {
+		return nil
+	}
+	delete(app.bans, unban.user)
+	slog.Debug("Unbanned user", "user", unban.user, "approvers", len(approvers))
+	if LogMembershipChanges {
+		app.Msgs = append(app.Msgs, Msg{Issuer: unban.issuer, Content: createControlMsgf(cyan, "%s was unbanned by %d members", b.prettyName, len(approvers))})
+	}
+	return nil
+}
+
+func (app *App) canUnban(op *Op) (bool, *Rejection) {
+	unban := op.content.(*UnbanOp)
+	if !app.hasPrevious(op) {
+		return false, reject(ReasonMissingPrevious, "previous operation ids do not exist")
+	} else if len(op.prevIds) == 0 {
+		return false, reject(ReasonNoPrevious, "unban operation must have at least one previous operation")
+	}
+	issuer := app.users[unban.issuer]
+	if issuer == nil {
+		return false, reject(ReasonNotAMember, "operation issuer is not a user")
+	} else if valid, reason := app.hasValidSignature(op, issuer.pubKey); !valid {
+		return false, reason
+	} else if !issuer.canWrite() {
+		return false, reject(ReasonReadOnly, "read-only users cannot unban users")
+	} else if app.bans[unban.user] == nil {
+		return false, reject(ReasonNotBanned, "user is not banned")
+	}
+	return true, nil
+}
@@ -0,0 +1,135 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/hashgraph"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldRejectAddingRemovedUser(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	remNode := hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{remNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(app.users))
+	assert.Nil(t, app.users[ids[1]])
+	removedBy, banned := app.BannedBy(ids[1])
+	assert.True(t, banned)
+	assert.Equal(t, remNode.GetId(), removedBy)
+	assert.Equal(t, ids[1:], app.Banned())
+}
+
+func TestShouldBanUserRemovedByVote(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 25)
+	proposeNode := hashgraph.NewNode(crdt.ProposeRemoval(ids[0], ids[2]), []*hashgraph.OpNode{addNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Nil(t, app.users[ids[2]])
+	removedBy, banned := app.BannedBy(ids[2])
+	assert.True(t, banned)
+	assert.Equal(t, proposeNode.GetId(), removedBy)
+}
+
+func TestShouldNotBanUserLeaving(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	leaveNode := hashgraph.NewNode(crdt.Leave(ids[1], nil), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{leaveNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(app.users))
+	assert.Empty(t, app.Banned())
+}
+
+func TestShouldUnbanWithApprovalOfMostPoints(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(4, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 20)
+	remNode := hashgraph.NewNode(crdt.Rem(ids[0], ids[3]), []*hashgraph.OpNode{addNode})
+	unban1Node := hashgraph.NewNode(crdt.Unban(ids[1], ids[3]), []*hashgraph.OpNode{remNode})
+	unban2Node := hashgraph.NewNode(crdt.Unban(ids[2], ids[3]), []*hashgraph.OpNode{unban1Node})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	_, banned := app.BannedBy(ids[3])
+	assert.True(t, banned)
+	hashgraph.NewNode(crdt.Unban(ids[0], ids[3]), []*hashgraph.OpNode{unban2Node})
+	crdt.Clear()
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err = ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	_, banned = app.BannedBy(ids[3])
+	assert.False(t, banned)
+}
+
+func TestShouldReAddUnbannedUser(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	remNode := hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{addNode})
+	unbanNode := hashgraph.NewNode(crdt.Unban(ids[0], ids[1]), []*hashgraph.OpNode{remNode})
+	hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{unbanNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(app.users))
+	assert.Empty(t, app.Banned())
+}
+
+func TestShouldRequireApprovalAboveQuorumToUnban(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 50)), []*hashgraph.OpNode{firstNode})
+	add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(50, 60)), []*hashgraph.OpNode{add1Node})
+	remNode := hashgraph.NewNode(crdt.Rem(ids[0], ids[2]), []*hashgraph.OpNode{add2Node})
+	hashgraph.NewNode(crdt.Unban(ids[1], ids[2]), []*hashgraph.OpNode{remNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 50, app.users[ids[1]].Points.Len())
+	_, banned := app.BannedBy(ids[2])
+	assert.True(t, banned)
+}
+
+func TestShouldLogUnban(t *testing.T) {
+	LogMembershipChanges = true
+	defer func() { LogMembershipChanges = false }()
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], "A"), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "B", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	remNode := hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Unban(ids[0], ids[1]), []*hashgraph.OpNode{remNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(app.Msgs))
+	assert.Contains(t, app.Msgs[3].Content, "B was unbanned by 1 members")
+}
@@ -0,0 +1,169 @@
+package accesscontrolapp
+
+import (
+	"github.com/google/uuid"
+	"github.com/petar/GoLLRB/llrb"
+	"github.com/samber/lo"
+	"log/slog"
+	"slices"
+)
+
+// Channels host separate conversations within the group. Each channel has as many points as the group, all held by
+// its creator when it is created. Channel members admit other group members into the channel by giving them some of
+// their channel points, just as members are added to the group. Channel membership is a subset of group membership:
+// when a user stops being a group member they are dropped from every channel, and their channel points are spread
+// across the remaining channel members in proportion to their stake.
+// Posts without a channel are seen by the whole group.
+
+// Channel is a conversation open only to its members.
+type Channel struct {
+	Id      uuid.UUID
+	Name    string
+	members map[uuid.UUID]*llrb.LLRB
+}
+
+// Members returns the ids of the channel members, sorted.
+func (c *Channel) Members() []uuid.UUID {
+	members := lo.Keys(c.members)
+	slices.SortFunc(members, compareIds)
+	return members
+}
+
+// PointsOf returns the channel points held by the user, or 0 if they are not a member of the channel.
+func (c *Channel) PointsOf(user uuid.UUID) int {
+	if points := c.members[user]; points != nil {
+		return points.Len()
+	}
+	return 0
+}
+
+// Channels returns the channels of the group, sorted by id.
+func (app *App) Channels() []*Channel {
+	channels := lo.Values(app.channels)
+	slices.SortFunc(channels, func(a, b *Channel) int { return compareIds(a.Id, b.Id) })
+	return channels
+}
+
+func (app *App) GetChannel(id uuid.UUID) *Channel {
+	return app.channels[id]
+}
+
+// ChannelMsgs returns the messages posted in the channel, in order. The channel with no id holds the messages
+// posted to the whole group.
+func (app *App) ChannelMsgs(channel uuid.UUID) []Msg {
+	return lo.Filter(app.Msgs, func(msg Msg, _ int) bool { return msg.Channel == channel })
+}
+
+// canAccess checks whether the user may post in the channel.
+func (app *App) canAccess(user, channel uuid.UUID) bool {
+	if channel == uuid.Nil {
+		return app.users[user] != nil
+	}
+	c := app.channels[channel]
+	return c != nil && c.members[user] != nil
+}
+
+func (app *App) createChannel(op *Op) error {
+	app.graphNodes[op.id] = app.dummyBNode(op)
+	create := op.content.(*CreateChannelOp)
+	if !app.hasPrevious(op) {
+		return reject(ReasonMissingPrevious, "previous operation ids do not exist")
+	} else if len(op.prevIds) == 0 {
+		return reject(ReasonNoPrevious, "create channel operation must have at least one previous operation")
+	}
+	issuer := app.users[create.issuer]
+	if issuer == nil {
+		return reject(ReasonNotAMember, "operation issuer is not a user")
+	} else if valid, reason := app.hasValidSignature(op, issuer.pubKey); !valid {
+		return reason
+	}
+	points := lo.Map(lo.Range(app.numPoints), func(p int, _ int) uint { return uint(p) })
+	app.channels[op.id] = &Channel{
+		Id:      op.id,
+		Name:    create.name,
+		members: map[uuid.UUID]*llrb.LLRB{create.issuer: newPointSet(points)},
+	}
+	slog.Debug("Created channel", "issuer", create.issuer, "channel", op.id, "name", create.name)
+	if LogMembershipChanges {
+		app.Msgs = append(app.Msgs, Msg{Issuer: create.issuer, Channel: op.id, Content: createControlMsgf(cyan, "%s created channel %s", issuer.prettyName, create.name)})
+	}
+	return nil
+}
+
+func (app *App) addToChannel(op *Op) error {
+	app.graphNodes[op.id] = app.dummyBNode(op)
+	add := op.content.(*AddToChannelOp)
+	if canAdd, reason := app.canAddToChannel(op); !canAdd {
+		return reason
+	}
+	channel := app.channels[add.channel]
+	issuerPoints := channel.members[add.issuer]
+	for _, p := range add.points {
+		issuerPoints.Delete(&pt{pt: int(p)})
+	}
+	channel.members[add.added] = newPointSet(add.points)
+	slog.Debug("Added user to channel", "issuer", add.issuer, "added", add.added, "channel", add.channel, "points", len(add.points))
+	app.emit(MemberAdded{Op: op.id, Issuer: add.issuer, Added: add.added, Channel: add.channel, Points: len(add.points)})
+	if LogMembershipChanges {
+		app.Msgs = append(app.Msgs, Msg{Issuer: add.issuer, Channel: add.channel, Content: createControlMsgf(cyan, "%s added %s to channel %s with %d points",
+			app.users[add.issuer].prettyName, app.users[add.added].prettyName, channel.Name, len(add.points))})
+	}
+	return nil
+}
+
+func (app *App) canAddToChannel(op *Op) (bool, *Rejection) {
+	if !app.hasPrevious(op) {
+		return false, reject(ReasonMissingPrevious, "previous operation ids do not exist")
+	} else if len(op.prevIds) == 0 {
+		return false, reject(ReasonNoPrevious, "add to channel operation must have at least one previous operation")
+	}
+	add := op.content.(*AddToChannelOp)
+	if add.issuer == add.added {
+		return false, reject(ReasonSelfTarget, "user cannot add themselves")
+	} else if len(add.points) == 0 {
+		return false, reject(ReasonInvalidPoints, "at least a single point must be given")
+	}
+	issuer := app.users[add.issuer]
+	channel := app.channels[add.channel]
+	if issuer == nil {
+		return false, reject(ReasonNotAMember, "operation issuer is not a user")
+	} else if valid, reason := app.hasValidSignature(op, issuer.pubKey); !valid {
+		return false, reason
+	} else if !issuer.canWrite() {
+		return false, reject(ReasonReadOnly, "read-only users cannot add users to channels")
+	} else if channel == nil {
+		return false, reject(ReasonUnknownTarget, "channel does not exist")
+	} else if channel.members[add.issuer] == nil {
+		return false, reject(ReasonNotInChannel, "issuer is not a member of the channel")
+	} else if canGive, reason := canGivePoints(channel.members[add.issuer], add.points); !canGive {
+		return false, reason
+	} else if app.users[add.added] == nil {
+		return false, reject(ReasonUnknownUser, "added user is not a member of the group")
+	} else if channel.members[add.added] != nil {
+		return false, reject(ReasonAlreadyMember, "added user is already a member of the channel")
+	}
+	return true, nil
+}
+
+// deleteUser removes the user from the group and from every channel they are a member of.
+func (app *App) deleteUser(id uuid.UUID) {
+	delete(app.users, id)
+	for _, channel := range app.Channels() {
+		points := channel.members[id]
+		if points == nil {
+			continue
+		}
+		delete(channel.members, id)
+		members := channel.Members()
+		if len(members) == 0 {
+			continue
+		}
+		stakes := lo.Map(members, func(member uuid.UUID, _ int) uint64 { return uint64(channel.PointsOf(member)) })
+		split := splitPoints(pointList(points), stakes)
+		for i, member := range members {
+			for _, p := range split[i] {
+				channel.members[member].InsertNoReplace(&pt{pt: int(p)})
+			}
+		}
+	}
+}
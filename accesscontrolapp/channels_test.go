@@ -0,0 +1,97 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/hashgraph"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldPostInChannel(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 10)
+	channelNode := hashgraph.NewNode(crdt.CreateChannel(ids[0], "general"), []*hashgraph.OpNode{addNode})
+	channel := channelNode.GetId()
+	admitNode := hashgraph.NewNode(crdt.AddToChannel(ids[0], channel, ids[1], makePtRange(0, 30)), []*hashgraph.OpNode{channelNode})
+	hashgraph.NewNode(crdt.PostToChannel(ids[1], channel, "inside"), []*hashgraph.OpNode{admitNode})
+	hashgraph.NewNode(crdt.PostToChannel(ids[2], channel, "outsider"), []*hashgraph.OpNode{admitNode})
+	hashgraph.NewNode(crdt.Post(ids[2], "everyone"), []*hashgraph.OpNode{admitNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(app.Channels()))
+	assert.Equal(t, "general", app.GetChannel(channel).Name)
+	assert.ElementsMatch(t, []uuid.UUID{ids[0], ids[1]}, app.GetChannel(channel).Members())
+	assert.Equal(t, 70, app.GetChannel(channel).PointsOf(ids[0]))
+	channelMsgs := app.ChannelMsgs(channel)
+	assert.Equal(t, 1, len(channelMsgs))
+	assert.Equal(t, "inside", channelMsgs[0].Text())
+	groupMsgs := app.ChannelMsgs(uuid.Nil)
+	assert.Equal(t, 1, len(groupMsgs))
+	assert.Equal(t, "everyone", groupMsgs[0].Text())
+}
+
+func TestShouldAdmitToChannelByPoints(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(4, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 10)
+	channelNode := hashgraph.NewNode(crdt.CreateChannel(ids[0], "general"), []*hashgraph.OpNode{addNode})
+	channel := channelNode.GetId()
+	admitNode := hashgraph.NewNode(crdt.AddToChannel(ids[0], channel, ids[1], makePtRange(0, 10)), []*hashgraph.OpNode{channelNode})
+	hashgraph.NewNode(crdt.AddToChannel(ids[1], channel, ids[2], makePtRange(0, 10)), []*hashgraph.OpNode{admitNode})
+	hashgraph.NewNode(crdt.AddToChannel(ids[1], channel, ids[3], makePtRange(50, 55)), []*hashgraph.OpNode{admitNode})
+	hashgraph.NewNode(crdt.AddToChannel(ids[2], channel, ids[3], makePtRange(0, 1)), []*hashgraph.OpNode{admitNode})
+	hashgraph.NewNode(crdt.AddToChannel(ids[1], channel, ids[0], makePtRange(0, 1)), []*hashgraph.OpNode{admitNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{ids[0], ids[1]}, app.GetChannel(channel).Members())
+}
+
+func TestShouldDropRemovedUsersFromChannels(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 10)
+	channelNode := hashgraph.NewNode(crdt.CreateChannel(ids[0], "general"), []*hashgraph.OpNode{addNode})
+	channel := channelNode.GetId()
+	admit1Node := hashgraph.NewNode(crdt.AddToChannel(ids[0], channel, ids[1], makePtRange(0, 20)), []*hashgraph.OpNode{channelNode})
+	admit2Node := hashgraph.NewNode(crdt.AddToChannel(ids[0], channel, ids[2], makePtRange(20, 40)), []*hashgraph.OpNode{admit1Node})
+	remNode := hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{admit2Node})
+	hashgraph.NewNode(crdt.PostToChannel(ids[1], channel, "still here?"), []*hashgraph.OpNode{remNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	c := app.GetChannel(channel)
+	assert.ElementsMatch(t, []uuid.UUID{ids[0], ids[2]}, c.Members())
+	assert.Equal(t, 75, c.PointsOf(ids[0]))
+	assert.Equal(t, 25, c.PointsOf(ids[2]))
+	assert.Empty(t, app.ChannelMsgs(channel))
+}
+
+func TestShouldPreventReadOnlyUsersFromAddingToChannels(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 10)
+	channelNode := hashgraph.NewNode(crdt.CreateChannel(ids[0], "general"), []*hashgraph.OpNode{addNode})
+	channel := channelNode.GetId()
+	admitNode := hashgraph.NewNode(crdt.AddToChannel(ids[0], channel, ids[1], makePtRange(0, 30)), []*hashgraph.OpNode{channelNode})
+	grantNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[1], RoleReadOnly), []*hashgraph.OpNode{admitNode})
+	hashgraph.NewNode(crdt.AddToChannel(ids[1], channel, ids[2], makePtRange(0, 10)), []*hashgraph.OpNode{grantNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{ids[0], ids[1]}, app.GetChannel(channel).Members())
+	rejected := app.RejectedBy(ids[1])
+	assert.Equal(t, 1, len(rejected))
+	assert.Equal(t, ReasonReadOnly, rejected[0].Rejection.Code)
+}
@@ -0,0 +1,226 @@
+package accesscontrolapp
+
+import (
+	"cmp"
+	"dare_randomized_access_control/cointoss"
+	"fmt"
+	"github.com/cloudflare/circl/group"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"log/slog"
+	"slices"
+)
+
+// A coin toss recovers the secrets of the deals of the latest refresh completed before it, hidden in a base derived
+// from its seed, and adds them up. Refreshes whose values can no longer be recovered, as too many of them were
+// encrypted to users who left the group since, are passed over for the latest one before them. The values dealt in
+// plaintext are hidden in the base by every replica. The values encrypted to their owners
+// are published by the owners alone, with coin share operations following the toss, each with a proof that it matches
+// the commitment of its dealer. Any threshold+1 values of a deal whose proofs verify recover its secret.
+// Until enough values are published for every deal, the coin toss is pending, and the operations it decides are
+// rejected. Once the owners publish their values, execution resumes from before the coin toss.
+
+// PendingCoinShare is a coin share an owner of values dealt has yet to publish for a pending coin toss.
+type PendingCoinShare struct {
+	Seed  int64
+	Owner uuid.UUID
+}
+
+// computeCoinToss recovers the secret combining the deals of the latest completed refresh, hidden in the base derived
+// from the seed.
+func (app *App) computeCoinToss(seed int64) (group.Element, error) {
+	deals := app.coinDeals()
+	if len(deals) == 0 {
+		return nil, fmt.Errorf("no refresh of the values was completed")
+	}
+	base := getECBase(seed)
+	published := app.publishedShares(seed)
+	g := group.Ristretto255
+	coin := g.Identity()
+	for _, id := range deals {
+		secret, err := app.recoverDealt(id, base, published[id])
+		if err != nil {
+			app.pendingCoins[seed] = deals
+			return nil, err
+		}
+		coin = g.NewElement().Add(coin, secret)
+	}
+	return coin, nil
+}
+
+// coinDeals returns the deals of the latest completed refresh whose deals can all still be recovered.
+func (app *App) coinDeals() []uuid.UUID {
+	for i := len(app.refreshOrder) - 1; i >= 0; i-- {
+		r := app.refreshes[app.refreshOrder[i]]
+		if !app.isCompleted(r) {
+			continue
+		} else if deals := app.dealsOf(r); lo.EveryBy(deals, app.canRecover) {
+			return deals
+		}
+	}
+	return nil
+}
+
+// canRecover checks whether threshold+1 values of the deal are either in plaintext or encrypted to users in the group.
+func (app *App) canRecover(id uuid.UUID) bool {
+	bnode := app.graphNodes[id]
+	available := lo.CountBy(lo.Range(len(bnode.deltaVals)), func(i int) bool {
+		return isPlain(bnode.deltaVals[i]) || app.users[bnode.encDeltaVals[i].owner] != nil
+	})
+	return available > app.threshold
+}
+
+// publishedShare is a coin share published by the issuer.
+type publishedShare struct {
+	issuer uuid.UUID
+	*coinShare
+}
+
+// publishedShares returns the coin shares published for the coin toss seeded by the seed, by deal and in the total
+// order. Only the coin share operations following the toss and signed by a user are considered.
+func (app *App) publishedShares(seed int64) map[uuid.UUID][]publishedShare {
+	published := make(map[uuid.UUID][]publishedShare)
+	for _, op := range app.coinShares[seed] {
+		reveal := op.content.(*CoinShareOp)
+		if issuer := app.users[reveal.issuer]; op.idx <= seed || issuer == nil {
+			continue
+		} else if valid, _ := app.hasValidSignature(op, issuer.pubKey); !valid {
+			continue
+		}
+		for _, share := range reveal.shares {
+			published[share.deal] = append(published[share.deal], publishedShare{issuer: reveal.issuer, coinShare: share})
+		}
+	}
+	return published
+}
+
+// recoverDealt recovers the secret of the deal hidden in the base, from its plaintext values and the values published
+// by the owners of those encrypted.
+func (app *App) recoverDealt(id uuid.UUID, base group.Element, published []publishedShare) (group.Element, error) {
+	bnode := app.graphNodes[id]
+	points := make(map[uint]cointoss.PointShare)
+	for i, val := range bnode.deltaVals {
+		if len(points) > app.threshold {
+			break
+		} else if isPlain(val) {
+			points[uint(i)] = cointoss.ShareToPoint(val, base)
+		}
+	}
+	for _, share := range published {
+		if len(points) > app.threshold {
+			break
+		} else if _, ok := points[share.point]; ok || !app.isPublishedByOwner(bnode, share) {
+			continue
+		} else if !share.share.Verify(cointoss.CommitmentToShare(share.share.ID(), bnode.commitment), base) {
+			slog.Warn("Skipping coin share that does not match the commitment of its dealer", "issuer", share.issuer, "deal", id, "point", share.point)
+			continue
+		}
+		points[share.point] = share.share.PointShare
+	}
+	if len(points) <= app.threshold {
+		return nil, fmt.Errorf("only %d values of deal %s were published, at least %d are required", len(points), id, app.threshold+1)
+	}
+	return cointoss.RecoverSecretFromPoints(lo.Values(points)), nil
+}
+
+// isPublishedByOwner checks that the share was published by the owner of the encrypted value of its point.
+func (app *App) isPublishedByOwner(bnode *backnode, share publishedShare) bool {
+	if share.point >= uint(len(bnode.encDeltaVals)) || bnode.encDeltaVals[share.point] == nil {
+		return false
+	}
+	return bnode.encDeltaVals[share.point].owner == share.issuer
+}
+
+// newCoinShares decrypts the values dealt to the owner by the deals of the pending coin toss seeded by the seed, and
+// hides them in its base. Values that do not match the commitment of their dealer are not published.
+func newCoinShares(owner uuid.UUID, seed int64, key cointoss.ShareKey, view *App) (*CoinShareOp, error) {
+	deals, pending := view.pendingCoins[seed]
+	if !pending {
+		return nil, fmt.Errorf("coin toss %d is not pending", seed)
+	} else if key.Private == nil {
+		return nil, fmt.Errorf("share key of %s is unknown", owner)
+	}
+	base := getECBase(seed)
+	shares := make([]*coinShare, 0)
+	for _, id := range deals {
+		bnode := view.graphNodes[id]
+		owned := lo.Filter(lo.Range(len(bnode.encDeltaVals)), func(i int, _ int) bool {
+			return bnode.encDeltaVals[i] != nil && bnode.encDeltaVals[i].owner == owner
+		})
+		values, err := cointoss.DecryptShares(lo.Map(owned, func(i int, _ int) cointoss.EncryptedShare { return bnode.encDeltaVals[i].share }), key.Private)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt values of deal %s: %v", id, err)
+		}
+		for j, value := range values {
+			if !cointoss.VerifyShare(uint(view.threshold), value, bnode.commitment) {
+				slog.Warn("Value dealt does not match the commitment of its dealer", "deal", id, "point", owned[j])
+				continue
+			}
+			share, err := cointoss.NewVerifiablePointShare(value, base)
+			if err != nil {
+				return nil, err
+			}
+			shares = append(shares, &coinShare{deal: id, point: uint(owned[j]), share: share})
+		}
+	}
+	return &CoinShareOp{issuer: owner, seed: seed, shares: shares}, nil
+}
+
+// revealCoinShares records the coin share operation, whose values were used by the coin toss it follows.
+func (app *App) revealCoinShares(op *Op) error {
+	app.graphNodes[op.id] = app.dummyBNode(op)
+	if !app.hasPrevious(op) {
+		return reject(ReasonMissingPrevious, "previous operation ids do not exist")
+	} else if len(op.prevIds) == 0 {
+		return reject(ReasonNoPrevious, "coin share operation must have at least one previous operation")
+	}
+	issuer := app.users[op.content.(*CoinShareOp).issuer]
+	if issuer == nil {
+		return reject(ReasonNotAMember, "operation issuer is not a user")
+	} else if valid, reason := app.hasValidSignature(op, issuer.pubKey); !valid {
+		return reason
+	}
+	return nil
+}
+
+// collectCoinShares indexes the coin share operations by the seed of their coin toss, so that coin tosses can use
+// the values published after them.
+func (app *App) collectCoinShares(opList []*Op) {
+	app.coinShares = make(map[int64][]*Op)
+	for _, op := range opList {
+		if op.kind == CoinShare {
+			seed := op.content.(*CoinShareOp).seed
+			app.coinShares[seed] = append(app.coinShares[seed], op)
+		}
+	}
+}
+
+// isPendingCoin checks whether the coin toss seeded by the seed lacked values.
+func (app *App) isPendingCoin(seed int64) bool {
+	_, pending := app.pendingCoins[seed]
+	return pending
+}
+
+// PendingCoinShares returns the owners still in the group of the values encrypted by the deals of the pending coin
+// tosses, sorted by the seed and then by the owner.
+func (app *App) PendingCoinShares() []PendingCoinShare {
+	pending := make([]PendingCoinShare, 0)
+	for seed, deals := range app.pendingCoins {
+		owners := lo.Uniq(lo.FlatMap(deals, func(id uuid.UUID, _ int) []uuid.UUID {
+			encrypted := lo.Compact(app.graphNodes[id].encDeltaVals)
+			return lo.FilterMap(encrypted, func(enc *encryptedDelta, _ int) (uuid.UUID, bool) {
+				return enc.owner, app.users[enc.owner] != nil
+			})
+		}))
+		for _, owner := range owners {
+			pending = append(pending, PendingCoinShare{Seed: seed, Owner: owner})
+		}
+	}
+	slices.SortFunc(pending, func(a, b PendingCoinShare) int {
+		if c := cmp.Compare(a.Seed, b.Seed); c != 0 {
+			return c
+		}
+		return compareIds(a.Owner, b.Owner)
+	})
+	return pending
+}
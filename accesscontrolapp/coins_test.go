@@ -0,0 +1,205 @@
+package accesscontrolapp
+
+import (
+	"crypto/ed25519"
+	"dare_randomized_access_control/hashgraph"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldWaitForCoinSharesOfOwners(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode, rems := encryptedRemovalNodes(&crdt, ids, genKeys(2, r))
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	// The owners have not published their values yet, so the removals cannot be ordered
+	assert.Equal(t, 2, len(app.users))
+	seed := lo.Min(lo.Map(rems, func(n *hashgraph.OpNode, _ int) int64 { return opOf(&crdt, n).idx }))
+	assert.ElementsMatch(t, []PendingCoinShare{{Seed: seed, Owner: ids[0]}, {Seed: seed, Owner: ids[1]}}, app.PendingCoinShares())
+	for _, rem := range rems {
+		rejection, ok := app.Rejection(rem.GetId())
+		assert.True(t, ok)
+		assert.Equal(t, ReasonCoinToss, rejection.Code)
+	}
+	revealNodes(&crdt, app, rems)
+	crdt.Clear()
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err = ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(app.users))
+	assert.Equal(t, 0, len(app.PendingCoinShares()))
+}
+
+func TestShouldSkipInvalidCoinShare(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	keys := genKeys(2, r)
+	firstNode, rems := encryptedRemovalNodes(&crdt, ids, keys)
+	hashgraph.RunHashgraph(0, firstNode)
+	pending, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	reveals := revealNodes(&crdt, pending, rems)
+	crdt.Clear()
+	hashgraph.RunHashgraph(0, firstNode)
+	honest, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	seed := pending.PendingCoinShares()[0].Seed
+	expected, err := honest.computeCoinToss(seed)
+	assert.NoError(t, err)
+	// The first owner publishes a wrong value of each deal, in place of the first one they own
+	reveal0, _ := lo.Find(reveals, func(n *hashgraph.OpNode) bool { return opOf(&crdt, n).issuer() == ids[0] })
+	op := opOf(&crdt, reveal0)
+	reveal := op.content.(*CoinShareOp)
+	firsts := lo.UniqBy(reveal.shares, func(s *coinShare) uuid.UUID { return s.deal })
+	for _, share := range firsts {
+		share.share.Point = reveal.shares[len(reveal.shares)-1].share.Point
+	}
+	op.sig = SignOp(keys[0], op.payload(), op.prevIds)
+	tampered, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	coin, err := tampered.computeCoinToss(seed)
+	assert.NoError(t, err)
+	assert.True(t, expected.IsEqual(coin))
+	assert.Equal(t, "", honest.Diff(tampered))
+	assert.Equal(t, 1, len(tampered.users))
+}
+
+func TestShouldRollBackToCoinTossOnCoinShares(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	executor := NewExecutor(&crdt, 100, 2)
+	firstNode, rems := encryptedRemovalNodes(&crdt, ids, genKeys(2, r))
+	hashgraph.RunHashgraph(0, firstNode)
+	_, err := executor.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(executor.App().users))
+	for _, node := range revealNodes(&crdt, executor.App(), rems) {
+		assert.NoError(t, node.ExecFunc())
+	}
+	changed, err := executor.Update()
+	assert.NoError(t, err)
+	assert.NotZero(t, changed&PartMembers)
+	assert.Equal(t, 1, len(executor.App().users))
+	assertSameAsFullExecution(t, &crdt, executor.App())
+}
+
+func TestShouldTossCoinAfterRemovingDealOwner(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	keys := genKeys(3, r)
+	for i, id := range ids {
+		crdt.SetKey(id, keys[i])
+	}
+	// The creator deals every value of the first refresh to themselves, and is removed once the others joined
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	initDeal := dealNode(&crdt, ids[0], firstNode, executedView(&crdt, firstNode))
+	add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 30)), []*hashgraph.OpNode{initDeal})
+	add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(30, 60)), []*hashgraph.OpNode{add1Node})
+	addDeals := committeeDealNode(&crdt, ids, add2Node, executedView(&crdt, firstNode))
+	remNode := hashgraph.NewNode(crdt.Rem(ids[1], ids[0]), []*hashgraph.OpNode{addDeals})
+	remDeals := committeeDealNode(&crdt, ids[1:], remNode, executedView(&crdt, firstNode))
+	rems := []*hashgraph.OpNode{
+		hashgraph.NewNode(crdt.Rem(ids[1], ids[2]), []*hashgraph.OpNode{remDeals}),
+		hashgraph.NewNode(crdt.Rem(ids[2], ids[1]), []*hashgraph.OpNode{remDeals}),
+	}
+	hashgraph.RunHashgraph(0, firstNode)
+	pending, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	seed := lo.Min(lo.Map(rems, func(n *hashgraph.OpNode, _ int) int64 { return opOf(&crdt, n).idx }))
+	// Only the remaining users owe coin shares, for the values dealt once the creator was removed
+	assert.ElementsMatch(t, []PendingCoinShare{{Seed: seed, Owner: ids[1]}, {Seed: seed, Owner: ids[2]}}, pending.PendingCoinShares())
+	assert.ElementsMatch(t, pending.dealsOf(pending.refreshes[remNode.GetId()]), pending.pendingCoins[seed])
+	revealNodes(&crdt, pending, rems)
+	crdt.Clear()
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(app.users))
+	assert.Equal(t, 0, len(app.PendingCoinShares()))
+	assert.False(t, lo.SomeBy(app.Rejected(), func(r RejectedOp) bool { return r.Rejection.Code == ReasonCoinToss }))
+}
+
+func TestShouldPassOverRefreshOwnedByRemovedUsers(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	keys := genKeys(3, r)
+	for i, id := range ids {
+		crdt.SetKey(id, keys[i])
+	}
+	// The values of the latest completed refresh were dealt to the creator but for two, and the creator is removed
+	// before the refresh of the removal completes
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 50)), []*hashgraph.OpNode{firstNode})
+	add1Deals := committeeDealNode(&crdt, ids[:2], add1Node, executedView(&crdt, firstNode))
+	add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(50, 51)), []*hashgraph.OpNode{add1Deals})
+	add2Deals := committeeDealNode(&crdt, ids, add2Node, executedView(&crdt, firstNode))
+	transferNode := hashgraph.NewNode(crdt.Transfer(ids[1], ids[0], makePtRange(0, 49)), []*hashgraph.OpNode{add2Deals})
+	transferDeals := committeeDealNode(&crdt, ids, transferNode, executedView(&crdt, firstNode))
+	remNode := hashgraph.NewNode(crdt.Rem(ids[2], ids[0]), []*hashgraph.OpNode{transferDeals})
+	rems := []*hashgraph.OpNode{
+		hashgraph.NewNode(crdt.Rem(ids[1], ids[2]), []*hashgraph.OpNode{remNode}),
+		hashgraph.NewNode(crdt.Rem(ids[2], ids[1]), []*hashgraph.OpNode{remNode}),
+	}
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	seed := lo.Min(lo.Map(rems, func(n *hashgraph.OpNode, _ int) int64 { return opOf(&crdt, n).idx }))
+	// The coin toss falls back on the refresh before the transfer, whose values the remaining users can recover
+	assert.ElementsMatch(t, app.dealsOf(app.refreshes[add2Node.GetId()]), app.pendingCoins[seed])
+	assert.ElementsMatch(t, []PendingCoinShare{{Seed: seed, Owner: ids[1]}, {Seed: seed, Owner: ids[2]}}, app.PendingCoinShares())
+}
+
+// encryptedRemovalNodes creates the nodes of two users with keys dealing values encrypted to each other and removing
+// each other concurrently. Returns the first node and the removal nodes, which are yet to be delivered.
+func encryptedRemovalNodes(crdt *CRDT, ids []uuid.UUID, keys []ed25519.PrivateKey) (*hashgraph.OpNode, []*hashgraph.OpNode) {
+	crdt.SetKey(ids[0], keys[0])
+	crdt.SetKey(ids[1], keys[1])
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 50)), []*hashgraph.OpNode{firstNode})
+	view := executedView(crdt, firstNode)
+	deals := []*hashgraph.OpNode{dealNode(crdt, ids[0], addNode, view), dealNode(crdt, ids[1], addNode, view)}
+	return firstNode, []*hashgraph.OpNode{
+		hashgraph.NewNode(crdt.Rem(ids[1], ids[0]), deals),
+		hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), deals),
+	}
+}
+
+// executedView delivers the nodes following the first node and returns the app executing them, as users see the
+// group when issuing their next operations. The nodes are cleared from the CRDT afterwards.
+func executedView(crdt *CRDT, firstNode *hashgraph.OpNode) *App {
+	hashgraph.RunHashgraph(0, firstNode)
+	view, err := ExecuteCRDT(crdt, 100, 2)
+	if err != nil {
+		panic(err)
+	}
+	crdt.Clear()
+	return view
+}
+
+// revealNodes creates the nodes with the coin shares the view lacks, following the nodes.
+func revealNodes(crdt *CRDT, view *App, prev []*hashgraph.OpNode) []*hashgraph.OpNode {
+	return lo.Map(view.PendingCoinShares(), func(pending PendingCoinShare, _ int) *hashgraph.OpNode {
+		return hashgraph.NewNode(crdt.RevealCoinShares(pending.Owner, pending.Seed, view), prev)
+	})
+}
+
+// opOf returns the operation of the node delivered to the CRDT.
+func opOf(crdt *CRDT, node *hashgraph.OpNode) *Op {
+	op, _ := lo.Find(crdt.GetOperationList(), func(op *Op) bool { return op.id == node.GetId() })
+	return op
+}
@@ -0,0 +1,146 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/cointoss"
+	"fmt"
+	"github.com/cloudflare/circl/group"
+	"github.com/google/uuid"
+	"github.com/negrel/assert"
+	"github.com/samber/lo"
+	"log/slog"
+	"slices"
+)
+
+// Removals with the same depth are concurrent, and the CRDT places them next to each other.
+// Among them, removals whose issuers and targets form a chain or a cycle conflict: executing one may prevent another,
+// so their outcome cannot depend on the order of their idx. Each set of conflicting removals is resolved with a
+// single coin toss, which draws a point-weighted order of their issuers. Issuers drawn earlier prevail over those drawn
+// later: issuers execute their removals in that order, unless they have been removed in the meantime, and removals
+// targeting an issuer drawn earlier are overridden. The first issuer drawn thus always survives, and each removal
+// between two issuers succeeds with probability equal to its issuer's share of their points.
+
+// concurrentRems returns the removals at the same depth as the removal at position i of the operation list.
+func concurrentRems(opList []*Op, i int) []*Op {
+	assert.Equal(Rem, opList[i].kind, "First operation must be removal when this method is called")
+	end := i + 1
+	for end < len(opList) && opList[end].kind == Rem && opList[end].depth() == opList[i].depth() {
+		end++
+	}
+	return opList[i:end]
+}
+
+// conflictSets partitions concurrent removals into sets where each removal targets the issuer of another removal in
+// the set, or is targeted by one. Sets are ordered by the idx of their first removal.
+func conflictSets(ops []*Op) [][]*Op {
+	parent := lo.Range(len(ops))
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i, op1 := range ops {
+		for j, op2 := range ops[:i] {
+			rem1 := op1.content.(*RemOp)
+			rem2 := op2.content.(*RemOp)
+			if rem1.removed == rem2.issuer || rem2.removed == rem1.issuer {
+				parent[find(i)] = find(j)
+			}
+		}
+	}
+	sets := lo.GroupBy(lo.Range(len(ops)), find)
+	roots := lo.Keys(sets)
+	slices.Sort(roots)
+	return lo.Map(roots, func(root int, _ int) []*Op {
+		return lo.Map(sets[root], func(i int, _ int) *Op { return ops[i] })
+	})
+}
+
+// remConcurrent executes removals at the same depth, resolving each set of conflicting removals with a coin toss.
+func (app *App) remConcurrent(ops []*Op) {
+	for _, set := range conflictSets(ops) {
+		if len(set) == 1 {
+			if err := app.rem(set[0]); err != nil {
+				slog.Warn("Unable to compute removal operation", "err", err, "idx", set[0].idx, "op", set[0].content.(*RemOp))
+				app.rejected(set[0], err)
+			}
+		} else if err := app.remConflicting(set); err != nil {
+			slog.Warn("Unable to compute concurrent removal operations", "err", err, "idx", set[0].idx)
+		}
+	}
+}
+
+func (app *App) remConflicting(ops []*Op) error {
+	valid := make([]*Op, 0, len(ops))
+	for _, op := range ops {
+		if canRem, reason := app.canRemUser(op); !canRem {
+			app.graphNodes[op.id] = app.dummyBNode(op)
+			slog.Warn("Unable to compute removal operation", "err", reason, "idx", op.idx, "op", op.content.(*RemOp))
+			app.rejected(op, reason)
+		} else {
+			valid = append(valid, op)
+		}
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+	issuers, coin, err := app.drawIssuerOrder(valid)
+	if err != nil {
+		rejection := reject(ReasonCoinToss, "%v", err)
+		lo.ForEach(valid, func(op *Op, _ int) {
+			app.graphNodes[op.id] = app.dummyBNode(op)
+			app.rejected(op, rejection)
+		})
+		return err
+	}
+	app.emit(ConcurrentRemovalResolved{Ops: lo.Map(valid, func(op *Op, _ int) uuid.UUID { return op.id }), Order: issuers, Coin: coin})
+	drawn := make(map[uuid.UUID]bool)
+	for _, issuer := range issuers {
+		drawn[issuer] = true
+		for _, op := range lo.Filter(valid, func(op *Op, _ int) bool { return op.content.(*RemOp).issuer == issuer }) {
+			if removed := op.content.(*RemOp).removed; drawn[removed] {
+				app.graphNodes[op.id] = app.dummyBNode(op)
+				prevailing, _ := lo.Find(valid, func(other *Op) bool { return other.issuer() == removed })
+				slog.Debug("Removal overridden by a concurrent removal", "idx", op.idx)
+				app.rejected(op, reject(ReasonOverridden, "removal targets an issuer drawn before its own").causedBy(prevailing.id))
+			} else if err := app.rem(op); err != nil {
+				slog.Debug("Removal overridden by a concurrent removal", "err", err, "idx", op.idx)
+				app.rejected(op, err)
+			}
+		}
+	}
+	return nil
+}
+
+// drawIssuerOrder orders the issuers of the operations with a single coin toss, weighting them by their points.
+// The first issuer is drawn with probability equal to their share of the points held by all issuers.
+// Returns the coin, which is nil if the order did not need a coin toss.
+func (app *App) drawIssuerOrder(ops []*Op) ([]uuid.UUID, group.Element, error) {
+	issuers := lo.Uniq(lo.Map(ops, func(op *Op, _ int) uuid.UUID { return op.issuer() }))
+	return app.drawOrder(issuers, ops[0].idx)
+}
+
+// drawOrder orders the issuers with a coin toss over the values dealt so far, seeded by the idx.
+func (app *App) drawOrder(issuers []uuid.UUID, seed int64) ([]uuid.UUID, group.Element, error) {
+	if len(issuers) == 1 {
+		return issuers, nil, nil
+	}
+	issuers = slices.Clone(issuers)
+	slices.SortFunc(issuers, compareIds)
+	// Issuers without points cannot be drawn, so they execute their removals last.
+	issuers, pointless := lo.FilterReject(issuers, func(issuer uuid.UUID, _ int) bool { return app.users[issuer].Points.Len() > 0 })
+	if len(issuers) <= 1 {
+		return append(issuers, pointless...), nil, nil
+	}
+	coin, err := app.computeCoinToss(seed)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to compute coin toss: %v", err)
+	}
+	weights := lo.Map(issuers, func(issuer uuid.UUID, _ int) uint64 { return uint64(app.users[issuer].Points.Len()) })
+	order, err := cointoss.PointWeightedOrder(coin, weights)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to draw order of removals: %v", err)
+	}
+	return append(lo.Map(order, func(i int, _ int) uuid.UUID { return issuers[i] }), pointless...), coin, nil
+}
@@ -1,16 +1,21 @@
 package accesscontrolapp
 
 import (
+	"crypto/ed25519"
 	"crypto/sha256"
+	"dare_randomized_access_control/cointoss"
 	"encoding/binary"
 	"fmt"
+	"github.com/cloudflare/circl/group"
+	"github.com/cloudflare/circl/secretsharing"
 	. "github.com/google/uuid"
 	"github.com/petar/GoLLRB/llrb"
+	"github.com/samber/lo"
 	"unsafe"
 )
 
 const u32Bits = int(unsafe.Sizeof(uint32(0))) * 8
-const opOffsetSize = 2
+const opOffsetSize = 4
 
 type OpType byte
 
@@ -19,14 +24,43 @@ const (
 	Post
 	Add
 	Rem
+	ProposeRemoval
+	Vote
+	Transfer
+	Leave
+	EditPost
+	DeletePost
+	Reply
+	React
+	CreateChannel
+	AddToChannel
+	GrantRole
+	RevokeRole
+	Unban
+	Deal
+	CoinShare
 )
 
 type OpOffset int
 
 const (
 	RemOffset OpOffset = iota
+	LeaveOffset
+	RoleOffset
+	ProposalOffset
+	VoteOffset
 	AddOffset
+	TransferOffset
+	CreateChannelOffset
+	AddToChannelOffset
 	PostOffset
+	ReplyOffset
+	ReactOffset
+	DeletePostOffset
+	EditPostOffset
+	UnbanOffset
+	// CoinOffset places the operations of the coin toss protocol after all others at the same depth
+	CoinOffset
 )
 
 type Op struct {
@@ -35,23 +69,78 @@ type Op struct {
 	content interface{}
 	id      UUID
 	prevIds []UUID
+	sig     []byte
 }
 
 type InitOp struct {
 	initial    UUID
 	prettyName string
+	pubKey     ed25519.PublicKey
+	shareKey   group.Element
 }
 
 type PostOp struct {
+	poster  UUID
+	channel UUID
+	msg     string
+}
+
+// CreateChannelOp creates a channel whose first member is the issuer, holding all of the channel's points.
+type CreateChannelOp struct {
+	issuer UUID
+	name   string
+}
+
+// AddToChannelOp admits a group member into a channel, giving them some of the issuer's points in the channel.
+type AddToChannelOp struct {
+	issuer  UUID
+	channel UUID
+	added   UUID
+	points  []uint
+}
+
+// ReplyOp posts a message in reply to the post created by the operation with the parent id.
+type ReplyOp struct {
 	poster UUID
+	parent UUID
 	msg    string
 }
 
+// ReactOp adds the issuer's reaction with the emoji to a post, or removes the reactions they had added before it.
+type ReactOp struct {
+	issuer UUID
+	post   UUID
+	emoji  string
+	remove bool
+}
+
+// EditPostOp replaces the text of the post created by the operation with the post id.
+type EditPostOp struct {
+	issuer UUID
+	post   UUID
+	msg    string
+}
+
+// DeletePostOp replaces the post created by the operation with the post id with a tombstone.
+type DeletePostOp struct {
+	issuer UUID
+	post   UUID
+}
+
 type AddOp struct {
 	issuer     UUID
 	added      UUID
 	points     []uint
 	prettyName string
+	pubKey     ed25519.PublicKey
+	shareKey   group.Element
+}
+
+// TransferOp moves points from the issuer to another member.
+type TransferOp struct {
+	issuer    UUID
+	recipient UUID
+	points    []uint
 }
 
 type RemOp struct {
@@ -59,27 +148,160 @@ type RemOp struct {
 	removed UUID
 }
 
+// LeaveOp removes the issuer from the group, handing their points to the heirs.
+// Without heirs, the points are spread across all remaining members.
+type LeaveOp struct {
+	issuer UUID
+	heirs  []UUID
+}
+
+// RoleOp changes the role of a user. Revoking a role returns the user to the member role.
+type RoleOp struct {
+	issuer UUID
+	user   UUID
+	role   Role
+	revoke bool
+}
+
+// ProposeRemovalOp opens a vote on removing the target, with the issuer voting in favour.
+type ProposeRemovalOp struct {
+	issuer UUID
+	target UUID
+}
+
+// VoteOp casts the issuer's vote on the removal proposed by the operation with the proposal id.
+type VoteOp struct {
+	issuer   UUID
+	proposal UUID
+	inFavour bool
+}
+
+// UnbanOp approves lifting the ban on a removed user, so that they can be added again.
+type UnbanOp struct {
+	issuer UUID
+	user   UUID
+}
+
+// DealOp contributes the values the issuer dealt to the refresh of the shares started by the operation with the
+// refresh id, along with the commitment to the polynomial they were dealt from. The issuer alone knows its secret.
+type DealOp struct {
+	issuer     UUID
+	refresh    UUID
+	commitment secretsharing.SecretCommitment
+	// deltaVals holds the values dealt, without a value for those encrypted
+	deltaVals []secretsharing.Share
+	// encDeltaVals holds the values encrypted to the owners of their points, as the issuer saw them. Nil if all are in plaintext.
+	encDeltaVals []*encryptedDelta
+	// encoded caches the payload, as encoding the commitment and the encrypted values is costly
+	encoded []byte
+}
+
+// CoinShareOp publishes the values dealt to the issuer for the coin toss seeded by the seed, hidden in the base the
+// seed derives, each with a proof that it matches the commitment of its dealer.
+type CoinShareOp struct {
+	issuer UUID
+	seed   int64
+	shares []*coinShare
+	// encoded caches the payload, as encoding the points and proofs is costly
+	encoded []byte
+}
+
+// coinShare is the value a deal dealt to the point, hidden in the base of a coin toss.
+type coinShare struct {
+	deal  UUID
+	point uint
+	share cointoss.VerifiablePointShare
+}
+
 type ConflictResolutionOp struct {
 	val float64
 }
 
+// issuer of the operation, whose key signs it. Operations of no known kind have no issuer.
+func (op *Op) issuer() UUID {
+	switch content := op.content.(type) {
+	case *InitOp:
+		return content.initial
+	case *PostOp:
+		return content.poster
+	case *ReplyOp:
+		return content.poster
+	case *ReactOp:
+		return content.issuer
+	case *EditPostOp:
+		return content.issuer
+	case *DeletePostOp:
+		return content.issuer
+	case *AddOp:
+		return content.issuer
+	case *TransferOp:
+		return content.issuer
+	case *LeaveOp:
+		return content.issuer
+	case *RoleOp:
+		return content.issuer
+	case *ProposeRemovalOp:
+		return content.issuer
+	case *VoteOp:
+		return content.issuer
+	case *CreateChannelOp:
+		return content.issuer
+	case *AddToChannelOp:
+		return content.issuer
+	case *UnbanOp:
+		return content.issuer
+	case *DealOp:
+		return content.issuer
+	case *CoinShareOp:
+		return content.issuer
+	case *RemOp:
+		return content.issuer
+	default:
+		return Nil
+	}
+}
+
+// depth of the operation in the hashgraph, recovered from its idx.
+func (op *Op) depth() int64 {
+	return op.idx >> (u32Bits + opOffsetSize)
+}
+
 func (op *Op) Less(other llrb.Item) bool {
 	otherOp := other.(*Op)
 	return op.idx < otherOp.idx
 }
 
 type CRDT struct {
-	tree *llrb.LLRB
+	tree         *llrb.LLRB
+	keys         map[UUID]ed25519.PrivateKey
+	pubKeys      map[UUID]ed25519.PublicKey
+	signatures   map[UUID][]byte
+	shareKeys    map[UUID]cointoss.ShareKey
+	sharePubKeys map[UUID]group.Element
+	log          *OpLog
+	// logged holds the ids of the operations in the attached log
+	logged   map[UUID]bool
+	executor *Executor
 }
 
 func NewCRDT() CRDT {
-	return CRDT{tree: llrb.New()}
+	return CRDT{
+		tree:         llrb.New(),
+		keys:         make(map[UUID]ed25519.PrivateKey),
+		pubKeys:      make(map[UUID]ed25519.PublicKey),
+		signatures:   make(map[UUID][]byte),
+		shareKeys:    make(map[UUID]cointoss.ShareKey),
+		sharePubKeys: make(map[UUID]group.Element),
+		logged:       make(map[UUID]bool),
+	}
 }
 
 func (crdt *CRDT) Init(firstParticipant UUID, prettyName string) func(depth int, id UUID, prevIds []UUID) error {
 	init := &InitOp{
 		initial:    firstParticipant,
 		prettyName: prettyName,
+		pubKey:     crdt.pubKeys[firstParticipant],
+		shareKey:   crdt.sharePubKeys[firstParticipant],
 	}
 	op := &Op{
 		idx:     0,
@@ -89,20 +311,28 @@ func (crdt *CRDT) Init(firstParticipant UUID, prettyName string) func(depth int,
 	}
 	return func(_ int, id UUID, _ []UUID) error {
 		op.id = id
+		op.sig = crdt.signature(op, firstParticipant)
 		if crdt.tree.ReplaceOrInsert(op) != nil {
 			return fmt.Errorf("init operation had already been issued")
 		}
-		return nil
+		crdt.notifyExecutor(op)
+		return crdt.appendToLog(op)
 	}
 }
 
 func (crdt *CRDT) Post(poster UUID, msg string) func(depth int, id UUID, prevIds []UUID) error {
+	return crdt.PostToChannel(poster, Nil, msg)
+}
+
+// PostToChannel posts a message visible only to the members of the channel.
+func (crdt *CRDT) PostToChannel(poster, channel UUID, msg string) func(depth int, id UUID, prevIds []UUID) error {
 	post := &PostOp{
-		poster: poster,
-		msg:    msg,
+		poster:  poster,
+		channel: channel,
+		msg:     msg,
 	}
 	return func(depth int, id UUID, prevIds []UUID) error {
-		idx, err := crdt.computePostIdx(depth, poster, msg)
+		idx, err := crdt.computePostIdx(depth, poster, append(channel[:], msg...))
 		if err != nil {
 			return fmt.Errorf("unable to compute operation index: %v", err)
 		}
@@ -113,14 +343,12 @@ func (crdt *CRDT) Post(poster UUID, msg string) func(depth int, id UUID, prevIds
 			id:      id,
 			prevIds: prevIds,
 		}
-		if crdt.tree.ReplaceOrInsert(op) != nil {
-			return fmt.Errorf("another operation had the same idx")
-		}
-		return nil
+		op.sig = crdt.signature(op, poster)
+		return crdt.deliver(op)
 	}
 }
 
-func (crdt *CRDT) computePostIdx(depth int, poster UUID, msg string) (int64, error) {
+func (crdt *CRDT) computePostIdx(depth int, poster UUID, msg []byte) (int64, error) {
 	var idx int64
 	idx = int64(depth << (u32Bits + opOffsetSize))
 	idx += int64(int(PostOffset) << u32Bits)
@@ -128,8 +356,7 @@ func (crdt *CRDT) computePostIdx(depth int, poster UUID, msg string) (int64, err
 	if err != nil {
 		return 0, fmt.Errorf("unable to marshal id of the message poster: %v", err)
 	}
-	msgBytes := []byte(msg)
-	offsetInput := append(idBytes, msgBytes...)
+	offsetInput := append(idBytes, msg...)
 	offset := hashToInt(offsetInput)
 	idx += int64(offset)
 	return idx, nil
@@ -141,6 +368,8 @@ func (crdt *CRDT) Add(issuer, added UUID, prettyName string, points []uint) func
 		added:      added,
 		points:     points,
 		prettyName: prettyName,
+		pubKey:     crdt.pubKeys[added],
+		shareKey:   crdt.sharePubKeys[added],
 	}
 	return func(depth int, id UUID, prevIds []UUID) error {
 		idx, err := crdt.computeAddIdx(depth, issuer, added, points)
@@ -154,10 +383,8 @@ func (crdt *CRDT) Add(issuer, added UUID, prettyName string, points []uint) func
 			id:      id,
 			prevIds: prevIds,
 		}
-		if crdt.tree.ReplaceOrInsert(op) != nil {
-			return fmt.Errorf("another operation had the same idx")
-		}
-		return nil
+		op.sig = crdt.signature(op, issuer)
+		return crdt.deliver(op)
 	}
 }
 
@@ -198,10 +425,8 @@ func (crdt *CRDT) Rem(issuer, removed UUID) func(depth int, id UUID, prevIds []U
 			id:      id,
 			prevIds: prevIds,
 		}
-		if crdt.tree.ReplaceOrInsert(op) != nil {
-			return fmt.Errorf("another operation had the same idx")
-		}
-		return nil
+		op.sig = crdt.signature(op, issuer)
+		return crdt.deliver(op)
 	}
 }
 
@@ -236,6 +461,327 @@ func (crdt *CRDT) computeRemIdx(depth int, issuer UUID, removed UUID) (int64, er
 	return idx, nil
 }
 
+func (crdt *CRDT) ProposeRemoval(issuer, target UUID) func(depth int, id UUID, prevIds []UUID) error {
+	propose := &ProposeRemovalOp{
+		issuer: issuer,
+		target: target,
+	}
+	return func(depth int, id UUID, prevIds []UUID) error {
+		op := &Op{
+			idx:     computeIdx(depth, ProposalOffset, append(issuer[:], target[:]...)),
+			kind:    ProposeRemoval,
+			content: propose,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, issuer)
+		return crdt.deliver(op)
+	}
+}
+
+func (crdt *CRDT) Vote(issuer, proposal UUID, inFavour bool) func(depth int, id UUID, prevIds []UUID) error {
+	vote := &VoteOp{
+		issuer:   issuer,
+		proposal: proposal,
+		inFavour: inFavour,
+	}
+	return func(depth int, id UUID, prevIds []UUID) error {
+		op := &Op{
+			idx:     computeIdx(depth, VoteOffset, vote.payload()),
+			kind:    Vote,
+			content: vote,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, issuer)
+		return crdt.deliver(op)
+	}
+}
+
+func (crdt *CRDT) Transfer(issuer, recipient UUID, points []uint) func(depth int, id UUID, prevIds []UUID) error {
+	transfer := &TransferOp{
+		issuer:    issuer,
+		recipient: recipient,
+		points:    points,
+	}
+	return func(depth int, id UUID, prevIds []UUID) error {
+		op := &Op{
+			idx:     computeIdx(depth, TransferOffset, transfer.payload()),
+			kind:    Transfer,
+			content: transfer,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, issuer)
+		return crdt.deliver(op)
+	}
+}
+
+func (crdt *CRDT) Leave(issuer UUID, heirs []UUID) func(depth int, id UUID, prevIds []UUID) error {
+	leave := &LeaveOp{
+		issuer: issuer,
+		heirs:  heirs,
+	}
+	return func(depth int, id UUID, prevIds []UUID) error {
+		op := &Op{
+			idx:     computeIdx(depth, LeaveOffset, leave.payload()),
+			kind:    Leave,
+			content: leave,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, issuer)
+		return crdt.deliver(op)
+	}
+}
+
+func (crdt *CRDT) CreateChannel(issuer UUID, name string) func(depth int, id UUID, prevIds []UUID) error {
+	create := &CreateChannelOp{
+		issuer: issuer,
+		name:   name,
+	}
+	return func(depth int, id UUID, prevIds []UUID) error {
+		op := &Op{
+			idx:     computeIdx(depth, CreateChannelOffset, create.payload()),
+			kind:    CreateChannel,
+			content: create,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, issuer)
+		return crdt.deliver(op)
+	}
+}
+
+func (crdt *CRDT) AddToChannel(issuer, channel, added UUID, points []uint) func(depth int, id UUID, prevIds []UUID) error {
+	add := &AddToChannelOp{
+		issuer:  issuer,
+		channel: channel,
+		added:   added,
+		points:  points,
+	}
+	return func(depth int, id UUID, prevIds []UUID) error {
+		op := &Op{
+			idx:     computeIdx(depth, AddToChannelOffset, add.payload()),
+			kind:    AddToChannel,
+			content: add,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, issuer)
+		return crdt.deliver(op)
+	}
+}
+
+func (crdt *CRDT) Reply(poster, parent UUID, msg string) func(depth int, id UUID, prevIds []UUID) error {
+	reply := &ReplyOp{
+		poster: poster,
+		parent: parent,
+		msg:    msg,
+	}
+	return func(depth int, id UUID, prevIds []UUID) error {
+		op := &Op{
+			idx:     computeIdx(depth, ReplyOffset, reply.payload()),
+			kind:    Reply,
+			content: reply,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, poster)
+		return crdt.deliver(op)
+	}
+}
+
+func (crdt *CRDT) React(issuer, post UUID, emoji string) func(depth int, id UUID, prevIds []UUID) error {
+	return crdt.react(&ReactOp{issuer: issuer, post: post, emoji: emoji})
+}
+
+func (crdt *CRDT) Unreact(issuer, post UUID, emoji string) func(depth int, id UUID, prevIds []UUID) error {
+	return crdt.react(&ReactOp{issuer: issuer, post: post, emoji: emoji, remove: true})
+}
+
+func (crdt *CRDT) react(react *ReactOp) func(depth int, id UUID, prevIds []UUID) error {
+	return func(depth int, id UUID, prevIds []UUID) error {
+		op := &Op{
+			idx:     computeIdx(depth, ReactOffset, append(react.payload(), id[:]...)),
+			kind:    React,
+			content: react,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, react.issuer)
+		return crdt.deliver(op)
+	}
+}
+
+func (crdt *CRDT) EditPost(issuer, post UUID, msg string) func(depth int, id UUID, prevIds []UUID) error {
+	edit := &EditPostOp{
+		issuer: issuer,
+		post:   post,
+		msg:    msg,
+	}
+	return func(depth int, id UUID, prevIds []UUID) error {
+		op := &Op{
+			idx:     computeIdx(depth, EditPostOffset, edit.payload()),
+			kind:    EditPost,
+			content: edit,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, issuer)
+		return crdt.deliver(op)
+	}
+}
+
+func (crdt *CRDT) DeletePost(issuer, post UUID) func(depth int, id UUID, prevIds []UUID) error {
+	del := &DeletePostOp{
+		issuer: issuer,
+		post:   post,
+	}
+	return func(depth int, id UUID, prevIds []UUID) error {
+		op := &Op{
+			idx:     computeIdx(depth, DeletePostOffset, del.payload()),
+			kind:    DeletePost,
+			content: del,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, issuer)
+		return crdt.deliver(op)
+	}
+}
+
+func (crdt *CRDT) GrantRole(issuer, user UUID, role Role) func(depth int, id UUID, prevIds []UUID) error {
+	return crdt.changeRole(&RoleOp{issuer: issuer, user: user, role: role})
+}
+
+func (crdt *CRDT) RevokeRole(issuer, user UUID) func(depth int, id UUID, prevIds []UUID) error {
+	return crdt.changeRole(&RoleOp{issuer: issuer, user: user, role: RoleMember, revoke: true})
+}
+
+func (crdt *CRDT) changeRole(change *RoleOp) func(depth int, id UUID, prevIds []UUID) error {
+	kind := lo.Ternary(change.revoke, RevokeRole, GrantRole)
+	return func(depth int, id UUID, prevIds []UUID) error {
+		op := &Op{
+			idx:     computeIdx(depth, RoleOffset, change.payload()),
+			kind:    kind,
+			content: change,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, change.issuer)
+		return crdt.deliver(op)
+	}
+}
+
+func (crdt *CRDT) Unban(issuer, user UUID) func(depth int, id UUID, prevIds []UUID) error {
+	unban := &UnbanOp{
+		issuer: issuer,
+		user:   user,
+	}
+	return func(depth int, id UUID, prevIds []UUID) error {
+		op := &Op{
+			idx:     computeIdx(depth, UnbanOffset, unban.payload()),
+			kind:    Unban,
+			content: unban,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, issuer)
+		return crdt.deliver(op)
+	}
+}
+
+// Deal deals random values for the refresh started by the operation with the refresh id, with the dealer as the
+// contributor. The values are drawn when the operation is created, from randomness only the dealer knows.
+// The view is the group as the dealer sees it.
+func (crdt *CRDT) Deal(dealer, refresh UUID, view *App) func(depth int, id UUID, prevIds []UUID) error {
+	deal, err := newDeal(dealer, refresh, view)
+	return func(depth int, id UUID, prevIds []UUID) error {
+		if err != nil {
+			return err
+		}
+		op := &Op{
+			idx:     computeIdx(depth, CoinOffset, deal.payload()),
+			kind:    Deal,
+			content: deal,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, dealer)
+		return crdt.deliver(op)
+	}
+}
+
+// RevealCoinShares publishes the values dealt to the owner for the pending coin toss seeded by the seed, which the
+// owner decrypts with their share key. The view is the group as the owner sees it.
+func (crdt *CRDT) RevealCoinShares(owner UUID, seed int64, view *App) func(depth int, id UUID, prevIds []UUID) error {
+	reveal, err := newCoinShares(owner, seed, crdt.shareKeys[owner], view)
+	return func(depth int, id UUID, prevIds []UUID) error {
+		if err != nil {
+			return err
+		}
+		op := &Op{
+			idx:     computeIdx(depth, CoinOffset, reveal.payload()),
+			kind:    CoinShare,
+			content: reveal,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, owner)
+		return crdt.deliver(op)
+	}
+}
+
+// DealPayload deals random values for the refresh like Deal, and returns the payload of the deal instead of
+// delivering it, for replicas sending the deals they issue to the others. DeliverCoinOp delivers it.
+func (crdt *CRDT) DealPayload(dealer, refresh UUID, view *App) ([]byte, error) {
+	deal, err := newDeal(dealer, refresh, view)
+	if err != nil {
+		return nil, err
+	}
+	return deal.payload(), nil
+}
+
+// CoinSharesPayload publishes the values dealt to the owner like RevealCoinShares, and returns the payload of the
+// coin shares instead of delivering them. DeliverCoinOp delivers it.
+func (crdt *CRDT) CoinSharesPayload(owner UUID, seed int64, view *App) ([]byte, error) {
+	reveal, err := newCoinShares(owner, seed, crdt.shareKeys[owner], view)
+	if err != nil {
+		return nil, err
+	}
+	return reveal.payload(), nil
+}
+
+// DeliverCoinOp delivers the deal or coin share operation with the payload, issued by another replica.
+func (crdt *CRDT) DeliverCoinOp(payload []byte) func(depth int, id UUID, prevIds []UUID) error {
+	kind, content, err := decodePayload(payload)
+	if err == nil && kind != Deal && kind != CoinShare {
+		err = fmt.Errorf("operation of kind %d is not part of the coin toss protocol", kind)
+	}
+	return func(depth int, id UUID, prevIds []UUID) error {
+		if err != nil {
+			return fmt.Errorf("unable to decode coin operation: %v", err)
+		}
+		op := &Op{
+			idx:     computeIdx(depth, CoinOffset, payload),
+			kind:    kind,
+			content: content,
+			id:      id,
+			prevIds: prevIds,
+		}
+		op.sig = crdt.signature(op, op.issuer())
+		return crdt.deliver(op)
+	}
+}
+
+// computeIdx places an operation at its depth and offset, ordering operations with the same offset by the hash of the input.
+func computeIdx(depth int, offset OpOffset, offsetInput []byte) int64 {
+	idx := int64(depth << (u32Bits + opOffsetSize))
+	idx += int64(int(offset) << u32Bits)
+	return idx + int64(hashToInt(offsetInput))
+}
+
 func (crdt *CRDT) GetOperationList() []*Op {
 	result := make([]*Op, 0, crdt.tree.Len())
 	smallestOp := &Op{idx: -1}
@@ -246,8 +792,22 @@ func (crdt *CRDT) GetOperationList() []*Op {
 	return result
 }
 
+// deliver inserts the operation in the tree, notifies the attached executor and appends the operation to the attached log.
+func (crdt *CRDT) deliver(op *Op) error {
+	if crdt.tree.ReplaceOrInsert(op) != nil {
+		return fmt.Errorf("another operation had the same idx")
+	}
+	crdt.notifyExecutor(op)
+	return crdt.appendToLog(op)
+}
+
+// Clear removes every operation from the tree. Operations delivered again are not appended to the attached log twice.
+// The attached executor discards the operations it executed.
 func (crdt *CRDT) Clear() {
 	crdt.tree = llrb.New()
+	if crdt.executor != nil {
+		crdt.executor.reset()
+	}
 }
 
 func hashToInt(b []byte) uint32 {
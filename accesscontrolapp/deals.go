@@ -0,0 +1,181 @@
+package accesscontrolapp
+
+import (
+	"crypto/rand"
+	"dare_randomized_access_control/cointoss"
+	"fmt"
+	"github.com/cloudflare/circl/secretsharing"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"log/slog"
+	"slices"
+)
+
+// Operations changing who owns the points refresh the values the coin tosses are computed from.
+// A refresh does not deal values itself. Instead, the threshold+1 users holding the most points once it executes form
+// its committee, and each of them contributes values of their own with a deal operation, dealt from a random secret
+// they alone know (joint-Feldman distributed key generation). A refresh completes once every committee member still in
+// the group has dealt to it. Coin tosses combine the deals of the latest refresh completed before them, so no group of
+// threshold users knows the secret behind them as long as one dealer keeps their secret.
+
+// refresh is the refresh of the values started by an operation.
+type refresh struct {
+	// committee holds the users contributing values to the refresh
+	committee []uuid.UUID
+	// dealt maps the committee members whose deal qualified to their deal
+	dealt map[uuid.UUID]uuid.UUID
+}
+
+// PendingDeal is a deal a committee member still owes to a refresh.
+type PendingDeal struct {
+	Refresh uuid.UUID
+	Dealer  uuid.UUID
+}
+
+// openRefresh starts the refresh of the values by the operation with the id, once it has been applied.
+func (app *App) openRefresh(id uuid.UUID) {
+	app.refreshes[id] = &refresh{committee: app.dealingCommittee(), dealt: make(map[uuid.UUID]uuid.UUID)}
+	app.refreshOrder = append(app.refreshOrder, id)
+}
+
+// isCompleted checks whether every member of the committee of the refresh still in the group dealt to it.
+func (app *App) isCompleted(r *refresh) bool {
+	return len(r.dealt) > 0 && lo.EveryBy(r.committee, func(member uuid.UUID) bool {
+		_, dealt := r.dealt[member]
+		return dealt || app.users[member] == nil
+	})
+}
+
+// dealsOf returns the deals of the refresh, in the order they were executed.
+func (app *App) dealsOf(r *refresh) []uuid.UUID {
+	dealt := lo.Values(r.dealt)
+	return lo.Filter(app.deals, func(id uuid.UUID, _ int) bool { return slices.Contains(dealt, id) })
+}
+
+// dealingCommittee returns the threshold+1 users holding the most points, breaking ties by id.
+func (app *App) dealingCommittee() []uuid.UUID {
+	members := app.Members()
+	slices.SortStableFunc(members, func(a, b *User) int { return b.Points.Len() - a.Points.Len() })
+	committee := members[:min(len(members), app.threshold+1)]
+	return lo.Map(committee, func(u *User, _ int) uuid.UUID { return u.Id })
+}
+
+// newDeal deals the values of the dealer for the refresh, drawing their secret from crypto/rand, and encrypts them
+// to the owners of their points in the view.
+func newDeal(dealer, refresh uuid.UUID, view *App) (*DealOp, error) {
+	contribution, err := cointoss.NewContribution(rand.Reader, uint(view.threshold), uint(view.numPoints))
+	if err != nil {
+		return nil, fmt.Errorf("unable to deal values: %v", err)
+	}
+	deltaVals, encDeltaVals, err := encryptDeltas(contribution.Shares, view)
+	if err != nil {
+		return nil, err
+	}
+	return &DealOp{
+		issuer:       dealer,
+		refresh:      refresh,
+		commitment:   contribution.Commitment,
+		deltaVals:    deltaVals,
+		encDeltaVals: encDeltaVals,
+	}, nil
+}
+
+func (app *App) deal(op *Op) error {
+	deal := op.content.(*DealOp)
+	if canDeal, reason := app.canDeal(op); !canDeal {
+		app.graphNodes[op.id] = app.dummyBNode(op)
+		return reason
+	}
+	bnode := &backnode{
+		id:           op.id,
+		deltaVals:    deal.deltaVals,
+		encDeltaVals: deal.encDeltaVals,
+		commitment:   deal.commitment,
+		prev:         lo.Map(op.prevIds, func(id uuid.UUID, _ int) *backnode { return app.graphNodes[id] }),
+	}
+	app.refreshes[deal.refresh].dealt[deal.issuer] = op.id
+	app.deals = append(app.deals, op.id)
+	app.graphNodes[op.id] = bnode
+	slog.Debug("Dealt values", "dealer", deal.issuer, "refresh", deal.refresh)
+	return nil
+}
+
+// canDeal checks that the deal qualifies: its dealer is in the committee of a refresh preceding it, has not dealt
+// to it before, and the values dealt in plaintext match the commitment published with them. The values encrypted are
+// checked against the commitment once their owners publish them.
+func (app *App) canDeal(op *Op) (bool, *Rejection) {
+	if !app.hasPrevious(op) {
+		return false, reject(ReasonMissingPrevious, "previous operation ids do not exist")
+	} else if len(op.prevIds) == 0 {
+		return false, reject(ReasonNoPrevious, "deal operation must have at least one previous operation")
+	}
+	deal := op.content.(*DealOp)
+	dealer := app.users[deal.issuer]
+	refresh := app.refreshes[deal.refresh]
+	if dealer == nil {
+		return false, reject(ReasonNotAMember, "operation issuer is not a user")
+	} else if valid, reason := app.hasValidSignature(op, dealer.pubKey); !valid {
+		return false, reason
+	} else if refresh == nil || !app.precedes(deal.refresh, op) {
+		return false, reject(ReasonUnknownTarget, "deal does not follow the refresh it contributes to")
+	} else if !slices.Contains(refresh.committee, deal.issuer) {
+		return false, reject(ReasonNotPermitted, "dealer is not in the committee of the refresh")
+	} else if earlier, dealt := refresh.dealt[deal.issuer]; dealt {
+		return false, reject(ReasonInvalidDeal, "dealer already contributed to the refresh").causedBy(earlier)
+	} else if len(deal.commitment) != app.threshold+1 || len(deal.deltaVals) != app.numPoints {
+		return false, reject(ReasonInvalidDeal, "deal must commit to a polynomial of degree %d and deal a value to each of the %d points", app.threshold, app.numPoints)
+	} else if lo.SomeBy(lo.Range(len(deal.deltaVals)), func(i int) bool { return !deal.deltaVals[i].ID.IsEqual(cointoss.NewScalar(uint64(i + 1))) }) {
+		return false, reject(ReasonInvalidDeal, "dealt values must be ordered by their point")
+	} else if !isEncryptionConsistent(deal) {
+		return false, reject(ReasonInvalidDeal, "each dealt value must be carried either in plaintext or encrypted")
+	} else if !cointoss.VerifyShares(uint(app.threshold), plainValues(deal), deal.commitment) {
+		return false, reject(ReasonInvalidDeal, "dealt values do not match the commitment of the dealer")
+	}
+	return true, nil
+}
+
+// PendingDeals returns the deals the members of the committees of the refreshes have yet to contribute, sorted by the
+// refresh and then by the dealer.
+func (app *App) PendingDeals() []PendingDeal {
+	pending := make([]PendingDeal, 0)
+	for id, refresh := range app.refreshes {
+		for _, dealer := range refresh.committee {
+			if _, dealt := refresh.dealt[dealer]; !dealt && app.users[dealer] != nil {
+				pending = append(pending, PendingDeal{Refresh: id, Dealer: dealer})
+			}
+		}
+	}
+	slices.SortFunc(pending, func(a, b PendingDeal) int {
+		if c := compareIds(a.Refresh, b.Refresh); c != 0 {
+			return c
+		}
+		return compareIds(a.Dealer, b.Dealer)
+	})
+	return pending
+}
+
+// isEncryptionConsistent checks that the deal carries each of its values either in plaintext or encrypted, with the id
+// of its point.
+func isEncryptionConsistent(deal *DealOp) bool {
+	if deal.encDeltaVals == nil {
+		return lo.EveryBy(deal.deltaVals, isPlain)
+	} else if len(deal.encDeltaVals) != len(deal.deltaVals) {
+		return false
+	}
+	return lo.EveryBy(lo.Range(len(deal.deltaVals)), func(i int) bool {
+		enc := deal.encDeltaVals[i]
+		if enc == nil {
+			return isPlain(deal.deltaVals[i])
+		}
+		return !isPlain(deal.deltaVals[i]) && enc.share.ID != nil && enc.share.ID.IsEqual(deal.deltaVals[i].ID)
+	})
+}
+
+func isPlain(val secretsharing.Share) bool {
+	return val.Value != nil
+}
+
+// plainValues returns the values the deal carries in plaintext.
+func plainValues(deal *DealOp) []secretsharing.Share {
+	return lo.Filter(deal.deltaVals, func(val secretsharing.Share, _ int) bool { return isPlain(val) })
+}
@@ -0,0 +1,162 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/cointoss"
+	"dare_randomized_access_control/hashgraph"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldOpenRefreshForCommittee(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 40)), []*hashgraph.OpNode{firstNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 0)
+	assert.NoError(t, err)
+	// With a threshold of 0, only the user holding the most points deals
+	assert.ElementsMatch(t, []PendingDeal{
+		{Refresh: firstNode.GetId(), Dealer: ids[0]},
+		{Refresh: addNode.GetId(), Dealer: ids[0]},
+	}, app.PendingDeals())
+	assert.Equal(t, 0, len(app.deals))
+}
+
+func TestShouldDealToRefresh(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 40)), []*hashgraph.OpNode{firstNode})
+	deal1 := dealNode(&crdt, ids[0], addNode, NewApp(100, 2))
+	deal2 := dealNode(&crdt, ids[1], addNode, NewApp(100, 2))
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(app.Rejected()))
+	assert.ElementsMatch(t, []uuid.UUID{deal1.GetId(), deal2.GetId()}, app.deals)
+	assert.Equal(t, []PendingDeal{{Refresh: firstNode.GetId(), Dealer: ids[0]}}, app.PendingDeals())
+	for _, id := range app.deals {
+		assert.Equal(t, 100, len(app.graphNodes[id].deltaVals))
+		assert.Equal(t, 3, len(app.graphNodes[id].commitment))
+	}
+}
+
+func TestShouldRejectDealOutsideCommittee(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 40)), []*hashgraph.OpNode{firstNode})
+	deal := dealNode(&crdt, ids[1], addNode, NewApp(100, 0))
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 0)
+	assert.NoError(t, err)
+	rejection, ok := app.Rejection(deal.GetId())
+	assert.True(t, ok)
+	assert.Equal(t, ReasonNotPermitted, rejection.Code)
+	assert.Equal(t, 0, len(app.deals))
+}
+
+func TestShouldRejectSecondDealToRefresh(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(1, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	deal1 := dealNode(&crdt, ids[0], firstNode, NewApp(100, 2))
+	deal2 := hashgraph.NewNode(crdt.Deal(ids[0], firstNode.GetId(), NewApp(100, 2)), []*hashgraph.OpNode{deal1})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	rejection, ok := app.Rejection(deal2.GetId())
+	assert.True(t, ok)
+	assert.Equal(t, ReasonInvalidDeal, rejection.Code)
+	assert.Equal(t, deal1.GetId(), rejection.Cause)
+	assert.Equal(t, []uuid.UUID{deal1.GetId()}, app.deals)
+}
+
+func TestShouldRejectDealOfWrongDegree(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(1, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	deal := dealNode(&crdt, ids[0], firstNode, NewApp(100, 1))
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	rejection, ok := app.Rejection(deal.GetId())
+	assert.True(t, ok)
+	assert.Equal(t, ReasonInvalidDeal, rejection.Code)
+}
+
+func TestShouldRejectTamperedDeal(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 40)), []*hashgraph.OpNode{firstNode})
+	honest := dealNode(&crdt, ids[0], addNode, NewApp(100, 2))
+	tampered := dealNode(&crdt, ids[1], addNode, NewApp(100, 2))
+	hashgraph.RunHashgraph(0, firstNode)
+	op, _ := lo.Find(crdt.GetOperationList(), func(op *Op) bool { return op.id == tampered.GetId() })
+	deal := op.content.(*DealOp)
+	deal.deltaVals[3].Value = cointoss.AddScalar(deal.deltaVals[3].Value, cointoss.NewScalar(1))
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	rejection, ok := app.Rejection(tampered.GetId())
+	assert.True(t, ok)
+	assert.Equal(t, ReasonInvalidDeal, rejection.Code)
+	assert.Equal(t, []uuid.UUID{honest.GetId()}, app.deals)
+	assert.Contains(t, app.PendingDeals(), PendingDeal{Refresh: addNode.GetId(), Dealer: ids[1]})
+}
+
+func TestShouldDeliverDealsIssuedByOtherReplicas(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	keys := genKeys(2, r)
+	crdt.SetKey(ids[0], keys[0])
+	crdt.SetKey(ids[1], keys[1])
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 40)), []*hashgraph.OpNode{firstNode})
+	view := executedView(&crdt, firstNode)
+	deals := lo.Map(ids, func(dealer uuid.UUID, _ int) *hashgraph.OpNode {
+		payload, err := crdt.DealPayload(dealer, addNode.GetId(), view)
+		assert.NoError(t, err)
+		return hashgraph.NewNode(crdt.DeliverCoinOp(payload), []*hashgraph.OpNode{addNode})
+	})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, lo.Map(deals, func(n *hashgraph.OpNode, _ int) uuid.UUID { return n.GetId() }), app.deals)
+	assert.True(t, app.isCompleted(app.refreshes[addNode.GetId()]))
+	// Operations outside the coin toss protocol are not delivered
+	assert.Error(t, crdt.DeliverCoinOp(PostPayload(ids[0], "hi"))(1, uuid.New(), []uuid.UUID{addNode.GetId()}))
+}
+
+// dealNode creates the node with the deal of the dealer to the refresh opened by the node, dealt in the view.
+func dealNode(crdt *CRDT, dealer uuid.UUID, refresh *hashgraph.OpNode, view *App) *hashgraph.OpNode {
+	return hashgraph.NewNode(crdt.Deal(dealer, refresh.GetId(), view), []*hashgraph.OpNode{refresh})
+}
+
+// committeeDealNode creates the nodes with the deals of the dealers to the refresh opened by the node, dealt in the
+// view, each following the one before. Returns the last of them.
+func committeeDealNode(crdt *CRDT, dealers []uuid.UUID, refresh *hashgraph.OpNode, view *App) *hashgraph.OpNode {
+	last := refresh
+	for _, dealer := range dealers {
+		last = hashgraph.NewNode(crdt.Deal(dealer, refresh.GetId(), view), []*hashgraph.OpNode{last})
+	}
+	return last
+}
@@ -0,0 +1,81 @@
+package accesscontrolapp
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"dare_randomized_access_control/cointoss"
+	"fmt"
+	"github.com/cloudflare/circl/group"
+	"github.com/cloudflare/circl/secretsharing"
+	. "github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+// The dealer encrypts the values it deals to the users owning the points as it sees them, and the deal operation
+// carries the ciphertexts, which every replica stores as they are. The values are never encrypted again. Instead, every
+// operation moving points opens a refresh, whose committee deals new values to the new owners, and coin tosses use the
+// deals of the latest completed refresh, so the users publishing the values follow the ownership of the points. Users
+// registering a share key when they join decrypt their own values only when a coin is tossed, and publish them with
+// coin share operations. The values of the points owned by users without a share key are dealt in plaintext.
+
+// encryptedDelta is a delta value encrypted to the user owning its point when it was dealt.
+type encryptedDelta struct {
+	owner UUID
+	share cointoss.EncryptedShare
+}
+
+// ShareKeyFromSigningKey derives the share key of a user from their signing key.
+func ShareKeyFromSigningKey(key ed25519.PrivateKey) cointoss.ShareKey {
+	return cointoss.DeriveShareKey(key.Seed())
+}
+
+// SetShareKey registers the key a user decrypts their values with.
+// Its public part is included in the operations adding the user to the group.
+func (crdt *CRDT) SetShareKey(user UUID, key cointoss.ShareKey) {
+	crdt.shareKeys[user] = key
+	crdt.sharePubKeys[user] = key.Public
+}
+
+// SetSharePublicKey registers the public share key included in the operations adding the user to the group.
+func (crdt *CRDT) SetSharePublicKey(user UUID, key group.Element) {
+	crdt.sharePubKeys[user] = key
+}
+
+// encryptDeltas encrypts the values dealt to the owners of their points in the view, and returns the values left in
+// plaintext alongside those encrypted. The encrypted values are nil if all are left in plaintext.
+func encryptDeltas(deltaVals []secretsharing.Share, view *App) ([]secretsharing.Share, []*encryptedDelta, error) {
+	owners := view.pointOwners()
+	encDeltaVals := make([]*encryptedDelta, len(deltaVals))
+	plain := append([]secretsharing.Share{}, deltaVals...)
+	byOwner := lo.GroupBy(lo.Range(len(deltaVals)), func(i int) UUID { return owners[i] })
+	for owner, idxs := range byOwner {
+		user := view.users[owner]
+		if user == nil || user.shareKey == nil {
+			continue
+		}
+		shares := lo.Map(idxs, func(i int, _ int) secretsharing.Share { return deltaVals[i] })
+		encrypted, err := cointoss.EncryptShares(shares, user.shareKey, rand.Reader)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to encrypt values to %s: %v", owner, err)
+		}
+		for j, i := range idxs {
+			encDeltaVals[i] = &encryptedDelta{owner: owner, share: encrypted[j]}
+			plain[i] = secretsharing.Share{ID: deltaVals[i].ID}
+		}
+	}
+	if lo.EveryBy(encDeltaVals, func(enc *encryptedDelta) bool { return enc == nil }) {
+		return deltaVals, nil, nil
+	}
+	return plain, encDeltaVals, nil
+}
+
+// pointOwners returns the user owning each point.
+func (app *App) pointOwners() []UUID {
+	owners := make([]UUID, app.numPoints)
+	for _, user := range app.users {
+		for _, p := range user.PointList() {
+			owners[p] = user.Id
+		}
+	}
+	return owners
+}
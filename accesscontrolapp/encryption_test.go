@@ -0,0 +1,110 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/cointoss"
+	"dare_randomized_access_control/hashgraph"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldEncryptDealtValuesToOwners(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	keys := genKeys(1, r)
+	crdt.SetKey(ids[0], keys[0])
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 40)), []*hashgraph.OpNode{firstNode})
+	deal := dealNode(&crdt, ids[0], addNode, executedView(&crdt, firstNode))
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	bnode := app.graphNodes[deal.GetId()]
+	// The first user has a share key, the second does not
+	assert.True(t, lo.EveryBy(lo.RangeFrom(40, 60), func(i int) bool {
+		return bnode.encDeltaVals[i] != nil && bnode.encDeltaVals[i].owner == ids[0] && bnode.deltaVals[i].Value == nil
+	}))
+	assert.True(t, lo.EveryBy(lo.Range(40), func(i int) bool {
+		return bnode.encDeltaVals[i] == nil && bnode.deltaVals[i].Value != nil
+	}))
+	// Only the owner decrypts their values, which match the commitment of the dealer
+	encrypted := lo.Map(bnode.encDeltaVals[40:], func(enc *encryptedDelta, _ int) cointoss.EncryptedShare { return enc.share })
+	values, err := cointoss.DecryptShares(encrypted, crdt.shareKeys[ids[0]].Private)
+	assert.NoError(t, err)
+	assert.True(t, cointoss.VerifyShares(2, values, bnode.commitment))
+	other := cointoss.DeriveShareKey([]byte("other"))
+	values, err = cointoss.DecryptShares(encrypted, other.Private)
+	assert.NoError(t, err)
+	assert.False(t, cointoss.VerifyShares(2, values, bnode.commitment))
+}
+
+func TestShouldStoreDealtCiphertextsVerbatim(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode, _ := encryptedRemovalNodes(&crdt, ids, genKeys(2, r))
+	hashgraph.RunHashgraph(0, firstNode)
+	opList := crdt.GetOperationList()
+	// A replica receiving the operations decodes the same ciphertexts the dealers encrypted
+	received := lo.Map(opList, func(op *Op, _ int) *Op {
+		decoded, err := decodeLoggedOp(encodeLoggedOp(op))
+		assert.NoError(t, err)
+		return decoded
+	})
+	app, err := ExecuteOpList(opList, 100, 2)
+	assert.NoError(t, err)
+	replica, err := ExecuteOpList(received, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(app.deals))
+	for _, id := range app.deals {
+		dealt, err := graphNodeSnapshot(app.graphNodes[id])
+		assert.NoError(t, err)
+		stored, err := graphNodeSnapshot(replica.graphNodes[id])
+		assert.NoError(t, err)
+		assert.NotNil(t, dealt.EncDeltaVals)
+		assert.Equal(t, dealt.EncDeltaVals, stored.EncDeltaVals)
+		assert.Equal(t, dealt.DeltaVals, stored.DeltaVals)
+	}
+}
+
+func TestShouldPublishValuesOfTransferredPoints(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	keys := genKeys(3, r)
+	for i, id := range ids {
+		crdt.SetKey(id, keys[i])
+	}
+	// The creator owns nearly every value dealt once the others joined, then gives almost all their points away
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 1)), []*hashgraph.OpNode{firstNode})
+	add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(1, 2)), []*hashgraph.OpNode{add1Node})
+	addDeals := committeeDealNode(&crdt, ids, add2Node, executedView(&crdt, firstNode))
+	transferNode := hashgraph.NewNode(crdt.Transfer(ids[0], ids[1], makePtRange(2, 99)), []*hashgraph.OpNode{addDeals})
+	transferDeals := committeeDealNode(&crdt, ids, transferNode, executedView(&crdt, firstNode))
+	rems := []*hashgraph.OpNode{
+		hashgraph.NewNode(crdt.Rem(ids[1], ids[2]), []*hashgraph.OpNode{transferDeals}),
+		hashgraph.NewNode(crdt.Rem(ids[2], ids[1]), []*hashgraph.OpNode{transferDeals}),
+	}
+	hashgraph.RunHashgraph(0, firstNode)
+	pending, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	// The values were dealt again to the new owners, so the creator need not publish theirs
+	for _, p := range pending.PendingCoinShares() {
+		if p.Owner != ids[0] {
+			hashgraph.NewNode(crdt.RevealCoinShares(p.Owner, p.Seed, pending), rems)
+		}
+	}
+	crdt.Clear()
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(app.users))
+	assert.Equal(t, 0, len(app.PendingCoinShares()))
+	assert.False(t, lo.SomeBy(app.Rejected(), func(r RejectedOp) bool { return r.Rejection.Code == ReasonCoinToss }))
+}
@@ -0,0 +1,113 @@
+package accesscontrolapp
+
+import (
+	"github.com/cloudflare/circl/group"
+	"github.com/google/uuid"
+	"slices"
+)
+
+// Apps emit events to the observers subscribed to them as they execute operations, so that clients react to changes
+// instead of comparing the state before and after an execution. Events are emitted once the change they report has
+// been applied. Executors emit RolledBack before executing operations again, and the events of those operations are
+// then emitted again.
+
+type Event interface {
+	isEvent()
+}
+
+// MemberAdded is emitted when a user joins the group or a channel. The creator of the group adds themselves.
+type MemberAdded struct {
+	Op     uuid.UUID
+	Issuer uuid.UUID
+	Added  uuid.UUID
+	// Channel the user joined, uuid.Nil if they joined the group
+	Channel uuid.UUID
+	Points  int
+}
+
+// MemberRemoved is emitted when a user leaves the group, is removed by another member or by vote.
+type MemberRemoved struct {
+	Op uuid.UUID
+	// Kind of the operation removing the user, one of Rem, Leave and Vote
+	Kind OpType
+	// Issuer of the removal, the leaving user or the proposer of the removal voted on
+	Issuer  uuid.UUID
+	Removed uuid.UUID
+}
+
+// PointsTransferred is emitted when a transfer operation moves points between members.
+// Points handed over when users leave or are removed are reported by MemberRemoved alone.
+type PointsTransferred struct {
+	Op     uuid.UUID
+	From   uuid.UUID
+	To     uuid.UUID
+	Points []uint
+}
+
+// MessagePosted is emitted when a message or a reply is posted.
+type MessagePosted struct {
+	Msg Msg
+}
+
+// OpRejected is emitted when an operation is not applied, including removals and role changes overridden by
+// concurrent ones.
+type OpRejected struct {
+	Op     uuid.UUID
+	Kind   OpType
+	Issuer uuid.UUID
+	Err    error
+}
+
+// ConcurrentRemovalResolved is emitted when a coin toss orders the issuers of conflicting removals, before the
+// removals are executed in that order.
+type ConcurrentRemovalResolved struct {
+	Ops   []uuid.UUID
+	Order []uuid.UUID
+	Coin  group.Element
+}
+
+// RolledBack is emitted by executors when they discard the operations executed after the first Applied ones.
+type RolledBack struct {
+	Applied int
+}
+
+func (MemberAdded) isEvent()               {}
+func (MemberRemoved) isEvent()             {}
+func (PointsTransferred) isEvent()         {}
+func (MessagePosted) isEvent()             {}
+func (OpRejected) isEvent()                {}
+func (ConcurrentRemovalResolved) isEvent() {}
+func (RolledBack) isEvent()                {}
+
+type Observer interface {
+	Notify(event Event)
+}
+
+// ObserverFunc adapts a function to the Observer interface.
+type ObserverFunc func(event Event)
+
+func (f ObserverFunc) Notify(event Event) {
+	f(event)
+}
+
+// Subscribe registers the observer to the events of the operations executed from now on.
+func (app *App) Subscribe(observer Observer) {
+	app.observers = append(app.observers, observer)
+}
+
+func (app *App) emit(event Event) {
+	for _, observer := range app.observers {
+		observer.Notify(event)
+	}
+}
+
+// Subscribe registers the observer to the events of the apps of the executor, including those it rolls back to.
+func (e *Executor) Subscribe(observer Observer) {
+	e.observers = append(e.observers, observer)
+	e.app.Subscribe(observer)
+}
+
+// observe subscribes the observers of the executor to its app.
+func (e *Executor) observe() {
+	e.app.observers = slices.Clone(e.observers)
+}
@@ -0,0 +1,138 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/hashgraph"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldEmitMembershipAndMessageEvents(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 10)
+	postNode := hashgraph.NewNode(crdt.Post(ids[1], "msg"), []*hashgraph.OpNode{addNode})
+	replyNode := hashgraph.NewNode(crdt.Reply(ids[2], postNode.GetId(), "reply"), []*hashgraph.OpNode{postNode})
+	transferNode := hashgraph.NewNode(crdt.Transfer(ids[0], ids[1], makePtRange(50, 55)), []*hashgraph.OpNode{replyNode})
+	leaveNode := hashgraph.NewNode(crdt.Leave(ids[2], nil), []*hashgraph.OpNode{transferNode})
+	remNode := hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{leaveNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	events := executeObserved(t, crdt.GetOperationList())
+	assert.Equal(t, []Event{
+		MemberAdded{Op: firstNode.GetId(), Issuer: ids[0], Added: ids[0], Points: 100},
+		MemberAdded{Op: firstNode.GetNext()[0].GetId(), Issuer: ids[0], Added: ids[1], Points: 10},
+		MemberAdded{Op: addNode.GetId(), Issuer: ids[0], Added: ids[2], Points: 10},
+		MessagePosted{Msg: Msg{Id: postNode.GetId(), Issuer: ids[1], Content: "msg"}},
+		MessagePosted{Msg: Msg{Id: replyNode.GetId(), Issuer: ids[2], Content: "reply", Parent: postNode.GetId()}},
+		PointsTransferred{Op: transferNode.GetId(), From: ids[0], To: ids[1], Points: makePtRange(50, 55)},
+		MemberRemoved{Op: leaveNode.GetId(), Kind: Leave, Issuer: ids[2], Removed: ids[2]},
+		MemberRemoved{Op: remNode.GetId(), Kind: Rem, Issuer: ids[0], Removed: ids[1]},
+	}, events)
+}
+
+func TestShouldEmitRemovalByVote(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 30)
+	proposeNode := hashgraph.NewNode(crdt.ProposeRemoval(ids[1], ids[0]), []*hashgraph.OpNode{addNode})
+	voteNode := hashgraph.NewNode(crdt.Vote(ids[2], proposeNode.GetId(), true), []*hashgraph.OpNode{proposeNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	events := executeObserved(t, crdt.GetOperationList())
+	assert.Equal(t, MemberRemoved{Op: voteNode.GetId(), Kind: Vote, Issuer: ids[1], Removed: ids[0]}, events[len(events)-1])
+}
+
+func TestShouldEmitRejectedOps(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	postNode := hashgraph.NewNode(crdt.Post(ids[1], "msg"), []*hashgraph.OpNode{firstNode})
+	hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(0, 200)), []*hashgraph.OpNode{postNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	events := executeObserved(t, crdt.GetOperationList())
+	rejected := filterEvents[OpRejected](events)
+	assert.Equal(t, 2, len(rejected))
+	assert.Equal(t, postNode.GetId(), rejected[0].Op)
+	assert.Equal(t, Post, rejected[0].Kind)
+	assert.Equal(t, ids[1], rejected[0].Issuer)
+	assert.Error(t, rejected[0].Err)
+	assert.Equal(t, Add, rejected[1].Kind)
+	assert.Equal(t, ids[0], rejected[1].Issuer)
+}
+
+func TestShouldEmitResolvedConcurrentRemovals(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 50)), []*hashgraph.OpNode{firstNode})
+	deal := committeeDealNode(&crdt, ids, addNode, NewApp(100, 2))
+	remNode1 := hashgraph.NewNode(crdt.Rem(ids[1], ids[0]), []*hashgraph.OpNode{deal})
+	remNode2 := hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{deal})
+	hashgraph.RunHashgraph(0, firstNode)
+	events := executeObserved(t, crdt.GetOperationList())
+	resolved := filterEvents[ConcurrentRemovalResolved](events)
+	assert.Equal(t, 1, len(resolved))
+	assert.ElementsMatch(t, []uuid.UUID{remNode1.GetId(), remNode2.GetId()}, resolved[0].Ops)
+	assert.ElementsMatch(t, ids, resolved[0].Order)
+	assert.NotNil(t, resolved[0].Coin)
+	removed := filterEvents[MemberRemoved](events)
+	assert.Equal(t, 1, len(removed))
+	assert.Equal(t, resolved[0].Order[0], removed[0].Issuer)
+	rejected := filterEvents[OpRejected](events)
+	assert.Equal(t, 1, len(rejected))
+	assert.Equal(t, resolved[0].Order[1], rejected[0].Issuer)
+}
+
+func TestShouldEmitEventsAgainAfterRollback(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	executor := NewExecutor(&crdt, 100, 2)
+	events := make([]Event, 0)
+	executor.Subscribe(ObserverFunc(func(event Event) { events = append(events, event) }))
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 30)), []*hashgraph.OpNode{firstNode})
+	postNode := hashgraph.NewNode(crdt.Post(ids[1], "msg"), []*hashgraph.OpNode{addNode})
+	lateNode := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(30, 40)), []*hashgraph.OpNode{firstNode})
+	for _, node := range []*hashgraph.OpNode{firstNode, addNode, postNode} {
+		assert.NoError(t, node.ExecFunc())
+	}
+	_, err := executor.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(events))
+	events = events[:0]
+	assert.NoError(t, lateNode.ExecFunc())
+	_, err = executor.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(events))
+	assert.Equal(t, RolledBack{Applied: 0}, events[0])
+	assert.Equal(t, 3, len(filterEvents[MemberAdded](events)))
+	assert.Equal(t, 1, len(filterEvents[MessagePosted](events)))
+}
+
+func executeObserved(t *testing.T, opList []*Op) []Event {
+	app := NewApp(100, 2)
+	events := make([]Event, 0)
+	app.Subscribe(ObserverFunc(func(event Event) { events = append(events, event) }))
+	assert.NoError(t, app.Execute(opList))
+	return events
+}
+
+func filterEvents[E Event](events []Event) []E {
+	filtered := make([]E, 0)
+	for _, event := range events {
+		if e, ok := event.(E); ok {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,239 @@
+package accesscontrolapp
+
+import (
+	"fmt"
+	"github.com/petar/GoLLRB/llrb"
+	"strings"
+)
+
+// An executor keeps an app up to date with the operations delivered to a CRDT, instead of executing every operation
+// again after each delivery. Operations delivered after the last one executed in the total order are executed
+// directly. An operation delivered before it, or resolved together with it, invalidates the operations executed since.
+// The executor then rolls back to the latest checkpoint taken before the operation and executes the operations after
+// the checkpoint again. Checkpoints are snapshots of the app, taken every CheckpointInterval operations.
+
+// DefaultCheckpointInterval is the number of operations executed between checkpoints of new executors.
+const DefaultCheckpointInterval = 64
+
+// StatePart is a set of parts of the state of an app.
+type StatePart uint
+
+const (
+	PartMembers StatePart = 1 << iota
+	PartBans
+	PartChannels
+	PartProposals
+	PartMsgs
+	PartReactions
+)
+
+var statePartNames = []string{"members", "bans", "channels", "proposals", "msgs", "reactions"}
+
+func (p StatePart) Has(part StatePart) bool {
+	return p&part == part
+}
+
+func (p StatePart) String() string {
+	names := make([]string, 0, len(statePartNames))
+	for i, name := range statePartNames {
+		if p.Has(1 << i) {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// partsOf returns the parts of the state an operation of the kind may change.
+// Operations changing the membership also change the messages if they are logged. Coin shares may settle the
+// conflicts between removals waiting for the coin.
+func partsOf(kind OpType) StatePart {
+	var parts StatePart
+	switch kind {
+	case Init, Add, Transfer, GrantRole, RevokeRole:
+		parts = PartMembers
+	case Rem, Vote, CoinShare:
+		parts = PartMembers | PartBans | PartChannels | PartProposals
+	case Leave:
+		parts = PartMembers | PartChannels
+	case Unban:
+		parts = PartBans
+	case ProposeRemoval:
+		return PartProposals
+	case CreateChannel, AddToChannel:
+		parts = PartChannels
+	case Post, Reply, EditPost, DeletePost:
+		return PartMsgs
+	case React:
+		return PartReactions
+	case Deal:
+		return 0
+	}
+	if LogMembershipChanges {
+		parts |= PartMsgs
+	}
+	return parts
+}
+
+type Executor struct {
+	// CheckpointInterval is the number of operations executed between checkpoints
+	CheckpointInterval int
+	crdt               *CRDT
+	numPoints          int
+	threshold          int
+	app                *App
+	// executed holds the operations executed, in the total order
+	executed    []*Op
+	checkpoints []*Snapshot
+	// earliest is the operation with the lowest idx delivered since the last update, nil if there is none
+	earliest  *Op
+	observers []Observer
+}
+
+// NewExecutor attaches an executor to the CRDT, replacing the executor attached before, if any.
+// The operations delivered to the CRDT before are executed on the first update.
+func NewExecutor(crdt *CRDT, numPoints, threshold int) *Executor {
+	e := &Executor{
+		CheckpointInterval: DefaultCheckpointInterval,
+		crdt:               crdt,
+		numPoints:          numPoints,
+		threshold:          threshold,
+	}
+	e.reset()
+	crdt.executor = e
+	if ops := crdt.GetOperationList(); len(ops) > 0 {
+		e.earliest = ops[0]
+	}
+	return e
+}
+
+// App returns the app resulting from the operations executed by the last update.
+func (e *Executor) App() *App {
+	return e.app
+}
+
+// Update executes the operations delivered since the last update. Returns the parts of the state changed by the
+// operations executed, including those of the operations rolled back. A part may be reported if the operations
+// acting on it were rejected.
+func (e *Executor) Update() (StatePart, error) {
+	if e.earliest == nil {
+		return 0, nil
+	}
+	earliest := e.earliest
+	e.earliest = nil
+	if e.app.applied == 0 || (earliest.idx > e.app.lastIdx && !resolvedTogether(e.executed[len(e.executed)-1], earliest)) {
+		return e.execute(e.pending())
+	}
+	return e.rollback(earliest)
+}
+
+// rollback restores the app from the latest checkpoint the operation does not invalidate, and executes the operations
+// after the checkpoint.
+func (e *Executor) rollback(earliest *Op) (StatePart, error) {
+	i := len(e.checkpoints) - 1
+	for i >= 0 && !e.precedes(e.checkpoints[i], earliest) {
+		i--
+	}
+	e.checkpoints = e.checkpoints[:i+1]
+	var checkpointed int
+	if i < 0 {
+		e.app = NewApp(e.numPoints, e.threshold)
+	} else {
+		app, err := e.checkpoints[i].restore()
+		if err != nil {
+			return 0, fmt.Errorf("unable to restore checkpoint: %v", err)
+		}
+		e.app = app
+		checkpointed = e.checkpoints[i].Applied
+	}
+	e.observe()
+	e.app.emit(RolledBack{Applied: checkpointed})
+	undone := e.executed[checkpointed:]
+	e.executed = e.executed[:checkpointed]
+	var changed StatePart
+	for _, op := range undone {
+		changed |= partsOf(op.kind)
+	}
+	executed, err := e.execute(e.pending())
+	return changed | executed, err
+}
+
+// precedes checks whether the operations executed before the checkpoint are unaffected by the operation.
+func (e *Executor) precedes(checkpoint *Snapshot, op *Op) bool {
+	return checkpoint.Idx < op.idx && !resolvedTogether(e.executed[checkpoint.Applied-1], op)
+}
+
+// execute applies the operations to the app, taking checkpoints along the way.
+func (e *Executor) execute(opList []*Op) (StatePart, error) {
+	var changed StatePart
+	interval := max(e.CheckpointInterval, 1)
+	for len(opList) > 0 {
+		lastCheckpoint := 0
+		if len(e.checkpoints) > 0 {
+			lastCheckpoint = e.checkpoints[len(e.checkpoints)-1].Applied
+		}
+		until := opList[min(len(opList), lastCheckpoint+interval-e.app.applied)-1].idx
+		applied := e.app.applied
+		err := e.app.execute(opList, until)
+		executed := opList[:e.app.applied-applied]
+		for _, op := range executed {
+			changed |= partsOf(op.kind)
+		}
+		e.executed = append(e.executed, executed...)
+		opList = opList[len(executed):]
+		if err != nil {
+			return changed, err
+		} else if e.app.applied-lastCheckpoint >= interval {
+			checkpoint, err := e.app.Snapshot()
+			if err != nil {
+				return changed, fmt.Errorf("unable to take checkpoint: %v", err)
+			}
+			e.checkpoints = append(e.checkpoints, checkpoint)
+		}
+	}
+	return changed, nil
+}
+
+// pending returns the operations after the last one executed.
+func (e *Executor) pending() []*Op {
+	if e.app.applied == 0 {
+		return e.crdt.GetOperationList()
+	}
+	return e.crdt.opsAfter(e.app.lastIdx)
+}
+
+// reset discards the operations executed, as they were cleared from the CRDT.
+func (e *Executor) reset() {
+	e.app = NewApp(e.numPoints, e.threshold)
+	e.observe()
+	e.executed = make([]*Op, 0)
+	e.checkpoints = make([]*Snapshot, 0)
+	e.earliest = nil
+	e.app.emit(RolledBack{Applied: 0})
+}
+
+func (e *Executor) delivered(op *Op) {
+	// A coin share for a pending coin toss invalidates the execution from the coin toss onwards
+	if reveal, ok := op.content.(*CoinShareOp); ok && reveal.seed < op.idx && e.app.isPendingCoin(reveal.seed) {
+		op = &Op{idx: reveal.seed, kind: CoinShare}
+	}
+	if e.earliest == nil || op.idx < e.earliest.idx {
+		e.earliest = op
+	}
+}
+
+// notifyExecutor tells the attached executor the operation was delivered.
+func (crdt *CRDT) notifyExecutor(op *Op) {
+	if crdt.executor != nil {
+		crdt.executor.delivered(op)
+	}
+}
+
+// opsAfter returns the operations with idx above the given one, in the total order.
+func (crdt *CRDT) opsAfter(idx int64) []*Op {
+	result := make([]*Op, 0)
+	crdt.tree.AscendGreaterOrEqual(&Op{idx: idx + 1}, func(i llrb.Item) bool {
+		result = append(result, i.(*Op))
+		return true
+	})
+	return result
+}
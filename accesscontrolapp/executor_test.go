@@ -0,0 +1,153 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/hashgraph"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldExecuteIncrementallyInCreationOrder(t *testing.T) {
+	LogMembershipChanges = false
+	crdt := NewCRDT()
+	nodes := snapshotNodes(&crdt, rand.New(rand.NewSource(int64(0))))
+	executor := NewExecutor(&crdt, 100, 2)
+	executor.CheckpointInterval = 2
+	for _, node := range nodes {
+		assert.NoError(t, node.ExecFunc())
+		_, err := executor.Update()
+		assert.NoError(t, err)
+		assertSameAsFullExecution(t, &crdt, executor.App())
+	}
+}
+
+func TestShouldExecuteIncrementallyInCausalOrder(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	for i := 0; i < 10; i++ {
+		crdt := NewCRDT()
+		nodes := snapshotNodes(&crdt, r)
+		executor := NewExecutor(&crdt, 100, 2)
+		executor.CheckpointInterval = 1 + r.Intn(4)
+		delivered := make(map[*hashgraph.OpNode]bool)
+		for len(delivered) < len(nodes) {
+			ready := make([]*hashgraph.OpNode, 0)
+			for _, node := range nodes {
+				if !delivered[node] && isReady(node, nodes, delivered) {
+					ready = append(ready, node)
+				}
+			}
+			node := ready[r.Intn(len(ready))]
+			assert.NoError(t, node.ExecFunc())
+			delivered[node] = true
+			_, err := executor.Update()
+			assert.NoError(t, err)
+			assertSameAsFullExecution(t, &crdt, executor.App())
+		}
+	}
+}
+
+func TestShouldReportChangedParts(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	executor := NewExecutor(&crdt, 100, 2)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 30)), []*hashgraph.OpNode{firstNode})
+	postNode := hashgraph.NewNode(crdt.Post(ids[1], "msg"), []*hashgraph.OpNode{addNode})
+	reactNode := hashgraph.NewNode(crdt.React(ids[0], postNode.GetId(), "👍"), []*hashgraph.OpNode{postNode})
+	lateNode := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(30, 40)), []*hashgraph.OpNode{firstNode})
+	changed, err := executor.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, StatePart(0), changed)
+	for _, node := range []*hashgraph.OpNode{firstNode, addNode} {
+		assert.NoError(t, node.ExecFunc())
+	}
+	changed, err = executor.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, PartMembers, changed)
+	assert.NoError(t, postNode.ExecFunc())
+	changed, err = executor.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, PartMsgs, changed)
+	assert.NoError(t, reactNode.ExecFunc())
+	changed, err = executor.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, PartReactions, changed)
+	assert.Equal(t, "reactions", changed.String())
+	// Lands before the post and the reaction, which are executed again
+	assert.NoError(t, lateNode.ExecFunc())
+	changed, err = executor.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, PartMembers|PartMsgs|PartReactions, changed)
+	assert.Equal(t, 3, len(executor.App().users))
+	assertSameAsFullExecution(t, &crdt, executor.App())
+}
+
+func TestShouldReportPartsChangedByCoinShares(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	keys := genKeys(2, r)
+	crdt.SetKey(ids[0], keys[0])
+	crdt.SetKey(ids[1], keys[1])
+	executor := NewExecutor(&crdt, 100, 2)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 50)), []*hashgraph.OpNode{firstNode})
+	deal := committeeDealNode(&crdt, ids, addNode, executedView(&crdt, firstNode))
+	rems := []*hashgraph.OpNode{
+		hashgraph.NewNode(crdt.Rem(ids[1], ids[0]), []*hashgraph.OpNode{deal}),
+		hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{deal}),
+	}
+	hashgraph.RunHashgraph(0, firstNode)
+	_, err := executor.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(executor.App().users))
+	for _, node := range revealNodes(&crdt, executor.App(), rems) {
+		assert.NoError(t, node.ExecFunc())
+	}
+	changed, err := executor.Update()
+	assert.NoError(t, err)
+	assert.Equal(t, PartMembers|PartBans|PartChannels|PartProposals, changed)
+	assert.Equal(t, 1, len(executor.App().users))
+	assertSameAsFullExecution(t, &crdt, executor.App())
+}
+
+func TestShouldExecuteOpsDeliveredBeforeAttaching(t *testing.T) {
+	LogMembershipChanges = false
+	crdt := NewCRDT()
+	nodes := snapshotNodes(&crdt, rand.New(rand.NewSource(int64(0))))
+	hashgraph.RunHashgraph(0, nodes[0])
+	executor := NewExecutor(&crdt, 100, 2)
+	_, err := executor.Update()
+	assert.NoError(t, err)
+	assertSameAsFullExecution(t, &crdt, executor.App())
+	crdt.Clear()
+	assert.Equal(t, 0, len(executor.App().users))
+	hashgraph.RunHashgraph(1, nodes[0])
+	_, err = executor.Update()
+	assert.NoError(t, err)
+	assertSameAsFullExecution(t, &crdt, executor.App())
+}
+
+// isReady checks whether every predecessor of the node has been delivered.
+func isReady(node *hashgraph.OpNode, nodes []*hashgraph.OpNode, delivered map[*hashgraph.OpNode]bool) bool {
+	for _, other := range nodes {
+		for _, next := range other.GetNext() {
+			if next.GetId() == node.GetId() && !delivered[other] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func assertSameAsFullExecution(t *testing.T, crdt *CRDT, app *App) {
+	expected, err := ExecuteCRDT(crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "", expected.Diff(app))
+	assert.Equal(t, expected.applied, app.applied)
+	assert.Equal(t, expected.digest, app.digest)
+}
@@ -0,0 +1,78 @@
+package accesscontrolapp
+
+import (
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"log/slog"
+	"slices"
+)
+
+// Members leave the group voluntarily, handing their points to heirs of their choosing, in equal parts, or to all
+// remaining members, in proportion to their stake. Heirs that are no longer members when the leave executes are
+// skipped, and if none remain, the points go to all remaining members.
+// Leaves are ordered after the removals at the same depth. A user removed concurrently with their leave has already
+// given their points to the remover, and the leave is rejected, so the points are never handed out twice.
+
+func (app *App) leave(op *Op) error {
+	leave := op.content.(*LeaveOp)
+	if canLeave, reason := app.canLeave(op); !canLeave {
+		app.graphNodes[op.id] = app.dummyBNode(op)
+		return reason
+	}
+	leaver := app.users[leave.issuer]
+	heirs, stakes := app.heirsOf(leave)
+	split := splitPoints(leaver.PointList(), stakes)
+	bnode := app.dummyBNode(op)
+	for i, heir := range heirs {
+		for _, p := range split[i] {
+			heir.Points.InsertNoReplace(&pt{pt: int(p)})
+		}
+	}
+	app.deleteUser(leave.issuer)
+	app.graphNodes[op.id] = bnode
+	app.openRefresh(op.id)
+	slog.Debug("User left", "issuer", leave.issuer, "heirs", len(heirs))
+	app.emit(MemberRemoved{Op: op.id, Kind: Leave, Issuer: leave.issuer, Removed: leave.issuer})
+	if LogMembershipChanges {
+		app.Msgs = append(app.Msgs, Msg{Issuer: leave.issuer, Content: createControlMsgf(cyan, "%s left the group, handing their points to %d members", leaver.prettyName, len(heirs))})
+	}
+	return nil
+}
+
+func (app *App) canLeave(op *Op) (bool, *Rejection) {
+	leave := op.content.(*LeaveOp)
+	if !app.hasPrevious(op) {
+		return false, reject(ReasonMissingPrevious, "previous operation ids do not exist")
+	} else if len(op.prevIds) == 0 {
+		return false, reject(ReasonNoPrevious, "leave operation must have at least one previous operation")
+	} else if slices.Contains(leave.heirs, leave.issuer) {
+		return false, reject(ReasonSelfTarget, "user cannot be their own heir")
+	}
+	issuer := app.users[leave.issuer]
+	if issuer == nil {
+		return false, reject(ReasonNotAMember, "operation issuer is not a user")
+	} else if len(app.users) == 1 {
+		return false, reject(ReasonLastMember, "last member cannot leave the group")
+	} else if valid, reason := app.hasValidSignature(op, issuer.pubKey); !valid {
+		return false, reason
+	}
+	return true, nil
+}
+
+// heirsOf returns the members receiving the points of the leaving user, sorted by id, and their stakes.
+func (app *App) heirsOf(leave *LeaveOp) ([]*User, []uint64) {
+	heirs := lo.FilterMap(lo.Uniq(leave.heirs), func(id uuid.UUID, _ int) (*User, bool) {
+		heir := app.users[id]
+		return heir, heir != nil
+	})
+	if len(heirs) > 0 {
+		slices.SortFunc(heirs, func(a, b *User) int { return compareIds(a.Id, b.Id) })
+		return heirs, lo.Map(heirs, func(_ *User, _ int) uint64 { return 1 })
+	}
+	heirs = lo.Filter(app.Members(), func(u *User, _ int) bool { return u.Id != leave.issuer })
+	stakes := lo.Map(heirs, func(u *User, _ int) uint64 { return uint64(u.Points.Len()) })
+	if lo.Sum(stakes) == 0 {
+		stakes = lo.Map(heirs, func(_ *User, _ int) uint64 { return 1 })
+	}
+	return heirs, stakes
+}
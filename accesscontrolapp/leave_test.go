@@ -0,0 +1,91 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/hashgraph"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldLeaveToHeirs(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(4, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 10)
+	leaveNode := hashgraph.NewNode(crdt.Leave(ids[0], []uuid.UUID{ids[1], ids[2]}), []*hashgraph.OpNode{addNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(app.users))
+	assert.Nil(t, app.users[ids[0]])
+	assert.Equal(t, 45, app.users[ids[1]].Points.Len())
+	assert.Equal(t, 45, app.users[ids[2]].Points.Len())
+	assert.Equal(t, 10, app.users[ids[3]].Points.Len())
+	// The leave refreshes the values, dealt by the remaining members
+	assert.ElementsMatch(t, ids[1:], app.refreshes[leaveNode.GetId()].committee)
+}
+
+func TestShouldLeaveToAllMembersByStake(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 20)), []*hashgraph.OpNode{firstNode})
+	add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(20, 80)), []*hashgraph.OpNode{add1Node})
+	hashgraph.NewNode(crdt.Leave(ids[0], nil), []*hashgraph.OpNode{add2Node})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(app.users))
+	assert.Equal(t, 25, app.users[ids[1]].Points.Len())
+	assert.Equal(t, 75, app.users[ids[2]].Points.Len())
+}
+
+func TestShouldSkipHeirsNoLongerMembers(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 10)
+	remNode := hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Leave(ids[2], []uuid.UUID{ids[1]}), []*hashgraph.OpNode{remNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(app.users))
+	assert.Equal(t, 100, app.users[ids[0]].Points.Len())
+}
+
+func TestShouldNotLeaveAfterConcurrentRemoval(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 10)
+	hashgraph.NewNode(crdt.Leave(ids[1], []uuid.UUID{ids[2]}), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{addNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(app.users))
+	assert.Equal(t, 90, app.users[ids[0]].Points.Len())
+	assert.Equal(t, 10, app.users[ids[2]].Points.Len())
+}
+
+func TestShouldFailToLeaveAsLastMember(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 10)
+	leaveNode := hashgraph.NewNode(crdt.Leave(ids[1], nil), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Leave(ids[0], nil), []*hashgraph.OpNode{leaveNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(app.users))
+	assert.Equal(t, 100, app.users[ids[0]].Points.Len())
+}
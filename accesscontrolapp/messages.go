@@ -0,0 +1,112 @@
+package accesscontrolapp
+
+import (
+	"github.com/google/uuid"
+	"log/slog"
+	"slices"
+)
+
+// Posts are edited and deleted by their poster, or by a member holding more points than the poster.
+// Edits apply in the order of the CRDT, so the text of a post is that of the last edit applied to it. Deletes replace
+// the post with a tombstone that keeps its place among the messages. At the same depth deletes come before edits, and
+// edits to a deleted post are rejected, so a delete always prevails over concurrent edits.
+
+// DeletedMsgText is the text shown in place of deleted messages.
+const DeletedMsgText = "message deleted"
+
+// Edit is a change made to the text of a message.
+type Edit struct {
+	Editor  uuid.UUID
+	Content string
+}
+
+// Text returns the text clients show for the message.
+func (m Msg) Text() string {
+	if m.Deleted {
+		return DeletedMsgText
+	}
+	return m.Content
+}
+
+// IsEdited checks whether the text of the message was changed since it was posted.
+func (m Msg) IsEdited() bool {
+	return len(m.Edits) > 0
+}
+
+func (m Msg) equal(other Msg) bool {
+	return m.Id == other.Id && m.Issuer == other.Issuer && m.Content == other.Content &&
+		m.Deleted == other.Deleted && slices.Equal(m.Edits, other.Edits) && m.Parent == other.Parent &&
+		m.Channel == other.Channel
+}
+
+// GetMsg returns the message created by the post operation with the id, or nil if there is none.
+func (app *App) GetMsg(id uuid.UUID) *Msg {
+	i, ok := app.msgIdx[id]
+	if !ok {
+		return nil
+	}
+	return &app.Msgs[i]
+}
+
+func (app *App) editPost(op *Op) error {
+	app.graphNodes[op.id] = app.dummyBNode(op)
+	edit := op.content.(*EditPostOp)
+	if canEdit, reason := app.canChangePost(op, edit.issuer, edit.post); !canEdit {
+		return reason
+	}
+	msg := app.GetMsg(edit.post)
+	msg.Edits = append(msg.Edits, Edit{Editor: edit.issuer, Content: edit.msg})
+	msg.Content = edit.msg
+	slog.Debug("Edited message", "editor", edit.issuer, "post", edit.post, "msg", edit.msg)
+	return nil
+}
+
+func (app *App) deletePost(op *Op) error {
+	app.graphNodes[op.id] = app.dummyBNode(op)
+	del := op.content.(*DeletePostOp)
+	if canDelete, reason := app.canChangePost(op, del.issuer, del.post); !canDelete {
+		return reason
+	}
+	msg := app.GetMsg(del.post)
+	msg.Content = ""
+	msg.Edits = nil
+	msg.Deleted = true
+	delete(app.reactions, del.post)
+	slog.Debug("Deleted message", "issuer", del.issuer, "post", del.post)
+	return nil
+}
+
+// canChangePost checks whether the issuer may edit or delete the post.
+func (app *App) canChangePost(op *Op, issuerId, post uuid.UUID) (bool, *Rejection) {
+	if !app.hasPrevious(op) {
+		return false, reject(ReasonMissingPrevious, "previous operation ids do not exist")
+	} else if len(op.prevIds) == 0 {
+		return false, reject(ReasonNoPrevious, "operation must have at least one previous operation")
+	}
+	issuer := app.users[issuerId]
+	msg := app.GetMsg(post)
+	if issuer == nil {
+		return false, reject(ReasonNotAMember, "operation issuer is not a user")
+	} else if valid, reason := app.hasValidSignature(op, issuer.pubKey); !valid {
+		return false, reason
+	} else if msg == nil {
+		return false, reject(ReasonUnknownTarget, "post does not exist")
+	} else if msg.Deleted {
+		return false, reject(ReasonDeleted, "post has been deleted")
+	} else if !app.canAccess(issuerId, msg.Channel) {
+		return false, reject(ReasonNotInChannel, "issuer is not a member of the channel")
+	} else if msg.Issuer != issuerId && !issuer.canWrite() {
+		return false, reject(ReasonReadOnly, "read-only users cannot change the posts of others")
+	} else if msg.Issuer != issuerId && issuer.Points.Len() <= app.pointsOf(msg.Issuer) {
+		return false, reject(ReasonNotPermitted, "only the poster or a member with more points than them can change the post")
+	}
+	return true, nil
+}
+
+// pointsOf returns the points held by the user, or 0 if they are not a member.
+func (app *App) pointsOf(id uuid.UUID) int {
+	if user := app.users[id]; user != nil {
+		return user.Points.Len()
+	}
+	return 0
+}
@@ -0,0 +1,107 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/hashgraph"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldEditPost(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(1, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	postNode := hashgraph.NewNode(crdt.Post(ids[0], "helo"), []*hashgraph.OpNode{firstNode})
+	editNode := hashgraph.NewNode(crdt.EditPost(ids[0], postNode.GetId(), "hello"), []*hashgraph.OpNode{postNode})
+	hashgraph.NewNode(crdt.EditPost(ids[0], postNode.GetId(), "hello!"), []*hashgraph.OpNode{editNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(app.Msgs))
+	msg := app.GetMsg(postNode.GetId())
+	assert.Equal(t, "hello!", msg.Text())
+	assert.True(t, msg.IsEdited())
+	assert.Equal(t, []Edit{{Editor: ids[0], Content: "hello"}, {Editor: ids[0], Content: "hello!"}}, msg.Edits)
+}
+
+func TestShouldDeletePost(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(1, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	postNode := hashgraph.NewNode(crdt.Post(ids[0], "secret"), []*hashgraph.OpNode{firstNode})
+	otherNode := hashgraph.NewNode(crdt.Post(ids[0], "public"), []*hashgraph.OpNode{postNode})
+	hashgraph.NewNode(crdt.DeletePost(ids[0], postNode.GetId()), []*hashgraph.OpNode{otherNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(app.Msgs))
+	assert.True(t, app.Msgs[0].Deleted)
+	assert.Equal(t, DeletedMsgText, app.Msgs[0].Text())
+	assert.Empty(t, app.Msgs[0].Content)
+	assert.Equal(t, "public", app.Msgs[1].Text())
+}
+
+func TestDeleteShouldPrevailOverConcurrentEdits(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	postNode := hashgraph.NewNode(crdt.Post(ids[1], "msg"), []*hashgraph.OpNode{addNode})
+	editNode := hashgraph.NewNode(crdt.EditPost(ids[1], postNode.GetId(), "edited"), []*hashgraph.OpNode{postNode})
+	hashgraph.NewNode(crdt.EditPost(ids[1], postNode.GetId(), "edited again"), []*hashgraph.OpNode{editNode})
+	hashgraph.NewNode(crdt.DeletePost(ids[0], postNode.GetId()), []*hashgraph.OpNode{postNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	msg := app.GetMsg(postNode.GetId())
+	assert.True(t, msg.Deleted)
+	assert.Empty(t, msg.Edits)
+}
+
+func TestShouldOnlyLetPosterOrLargerMembersChangePost(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 30)
+	bigPostNode := hashgraph.NewNode(crdt.Post(ids[0], "big"), []*hashgraph.OpNode{addNode})
+	smallPostNode := hashgraph.NewNode(crdt.Post(ids[1], "small"), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.EditPost(ids[1], bigPostNode.GetId(), "vandalized"), []*hashgraph.OpNode{bigPostNode})
+	hashgraph.NewNode(crdt.DeletePost(ids[2], smallPostNode.GetId()), []*hashgraph.OpNode{smallPostNode})
+	hashgraph.NewNode(crdt.EditPost(ids[0], smallPostNode.GetId(), "moderated"), []*hashgraph.OpNode{smallPostNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "big", app.GetMsg(bigPostNode.GetId()).Text())
+	assert.False(t, app.GetMsg(smallPostNode.GetId()).Deleted)
+	assert.Equal(t, "moderated", app.GetMsg(smallPostNode.GetId()).Text())
+}
+
+func TestShouldPreventReadOnlyUsersFromChangingPostsOfOthers(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 50)), []*hashgraph.OpNode{firstNode})
+	add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(50, 60)), []*hashgraph.OpNode{add1Node})
+	postNode := hashgraph.NewNode(crdt.Post(ids[2], "small"), []*hashgraph.OpNode{add2Node})
+	grantNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[1], RoleReadOnly), []*hashgraph.OpNode{postNode})
+	hashgraph.NewNode(crdt.EditPost(ids[1], postNode.GetId(), "vandalized"), []*hashgraph.OpNode{grantNode})
+	hashgraph.NewNode(crdt.DeletePost(ids[1], postNode.GetId()), []*hashgraph.OpNode{grantNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "small", app.GetMsg(postNode.GetId()).Text())
+	assert.False(t, app.GetMsg(postNode.GetId()).Deleted)
+	rejected := app.RejectedBy(ids[1])
+	assert.Equal(t, 2, len(rejected))
+	assert.Equal(t, ReasonReadOnly, rejected[0].Rejection.Code)
+	assert.Equal(t, ReasonReadOnly, rejected[1].Rejection.Code)
+}
@@ -0,0 +1,203 @@
+package accesscontrolapp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// The operation log is an append-only file holding the operations delivered to a CRDT, so that they survive restarts.
+// Each record is the length of an encoded operation and its CRC-32C checksum, followed by the operation: the
+// opLogVersion, its idx, id, previous ids, signature and payload. Records are synced to disk before an append returns. A crash may leave the last record partially written:
+// when the log is opened, it is truncated at the first record that is incomplete or fails its checksum.
+
+const recordHeaderSize = 8
+
+// opLogVersion is the version of the encoding of the logged operations, written first in every record.
+const opLogVersion byte = 1
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// OpLog is a file-backed, append-only log of operations.
+type OpLog struct {
+	file *os.File
+}
+
+// OpenOpLog opens the log at the path, creating it if it does not exist, and returns the operations it holds in the
+// order they were appended.
+func OpenOpLog(path string) (*OpLog, []*Op, error) {
+	_, statErr := os.Stat(path)
+	created := os.IsNotExist(statErr)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to open operation log: %v", err)
+	}
+	if created {
+		if err := syncDir(filepath.Dir(path)); err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("unable to read operation log: %v", err)
+	}
+	ops, valid, err := readRecords(data)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	} else if valid < len(data) {
+		if err := truncate(file, int64(valid)); err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+	}
+	return &OpLog{file: file}, ops, nil
+}
+
+// readRecords decodes the operations in the records, stopping at the first incomplete or corrupted record.
+// Returns the operations and the length of the valid records. Records that pass their checksum but cannot be decoded
+// are not truncated away, and fail the read instead.
+func readRecords(data []byte) ([]*Op, int, error) {
+	ops := make([]*Op, 0)
+	offset := 0
+	for len(data)-offset >= recordHeaderSize {
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		checksum := binary.BigEndian.Uint32(data[offset+4:])
+		body := data[offset+recordHeaderSize:]
+		if len(body) < length || crc32.Checksum(body[:length], crcTable) != checksum {
+			break
+		}
+		op, err := decodeLoggedOp(body[:length])
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to decode logged operation at offset %d: %v", offset, err)
+		}
+		ops = append(ops, op)
+		offset += recordHeaderSize + length
+	}
+	return ops, offset, nil
+}
+
+func encodeLoggedOp(op *Op) []byte {
+	b := []byte{opLogVersion}
+	b = binary.BigEndian.AppendUint64(b, uint64(op.idx))
+	b = append(b, op.id[:]...)
+	b = appendIds(b, op.prevIds)
+	b = appendBytes(b, op.sig)
+	return appendBytes(b, op.payload())
+}
+
+func decodeLoggedOp(data []byte) (*Op, error) {
+	d := &decoder{b: data}
+	if version := d.byte(); d.err == nil && version != opLogVersion {
+		return nil, fmt.Errorf("unsupported operation log version %d", version)
+	}
+	idx := int64(d.uint64())
+	id := d.uuid()
+	prevIds := d.ids()
+	sig := d.bytes()
+	payload := d.bytes()
+	if err := d.done(); err != nil {
+		return nil, err
+	}
+	kind, content, err := decodePayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Op{idx: idx, kind: kind, content: content, id: id, prevIds: prevIds, sig: lo.Ternary(len(sig) == 0, nil, sig)}, nil
+}
+
+func truncate(file *os.File, size int64) error {
+	if err := file.Truncate(size); err != nil {
+		return fmt.Errorf("unable to truncate operation log: %v", err)
+	} else if err := file.Sync(); err != nil {
+		return fmt.Errorf("unable to sync operation log: %v", err)
+	}
+	return nil
+}
+
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open operation log directory: %v", err)
+	}
+	defer dir.Close()
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("unable to sync operation log directory: %v", err)
+	}
+	return nil
+}
+
+// Append writes the operation at the end of the log and syncs it to disk.
+func (l *OpLog) Append(op *Op) error {
+	body := encodeLoggedOp(op)
+	record := binary.BigEndian.AppendUint32(nil, uint32(len(body)))
+	record = binary.BigEndian.AppendUint32(record, crc32.Checksum(body, crcTable))
+	record = append(record, body...)
+	if _, err := l.file.Write(record); err != nil {
+		return fmt.Errorf("unable to append to operation log: %v", err)
+	} else if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("unable to sync operation log: %v", err)
+	}
+	return nil
+}
+
+func (l *OpLog) Close() error {
+	return l.file.Close()
+}
+
+// AttachLog opens the operation log at the path and delivers the operations it holds.
+// Operations delivered afterwards are appended to the log.
+func (crdt *CRDT) AttachLog(path string) error {
+	if crdt.log != nil {
+		return fmt.Errorf("an operation log is already attached")
+	}
+	log, ops, err := OpenOpLog(path)
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		if prev := crdt.tree.ReplaceOrInsert(op); prev != nil && prev.(*Op).id != op.id {
+			crdt.tree.ReplaceOrInsert(prev)
+			log.Close()
+			return fmt.Errorf("logged operation %s had the same idx as operation %s", op.id, prev.(*Op).id)
+		}
+		crdt.logged[op.id] = true
+		crdt.notifyExecutor(op)
+	}
+	crdt.log = log
+	for _, op := range crdt.GetOperationList() {
+		if err := crdt.appendToLog(op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DetachLog closes the attached operation log. Operations delivered afterwards are only kept in memory.
+func (crdt *CRDT) DetachLog() error {
+	if crdt.log == nil {
+		return nil
+	}
+	err := crdt.log.Close()
+	crdt.log = nil
+	crdt.logged = make(map[uuid.UUID]bool)
+	return err
+}
+
+func (crdt *CRDT) appendToLog(op *Op) error {
+	if crdt.log == nil || crdt.logged[op.id] {
+		return nil
+	}
+	if err := crdt.log.Append(op); err != nil {
+		return err
+	}
+	crdt.logged[op.id] = true
+	return nil
+}
@@ -0,0 +1,215 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/hashgraph"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShouldReloadAppendedOps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ops.log")
+	log, ops, err := OpenOpLog(path)
+	assert.NoError(t, err)
+	assert.Empty(t, ops)
+	appended := everyOpKind()
+	for _, op := range appended {
+		assert.NoError(t, log.Append(op))
+	}
+	assert.NoError(t, log.Close())
+	log, ops, err = OpenOpLog(path)
+	assert.NoError(t, err)
+	defer log.Close()
+	assert.Equal(t, len(appended), len(ops))
+	for i := range ops {
+		assertSameOp(t, appended[i], ops[i])
+	}
+}
+
+func TestShouldTruncateTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ops.log")
+	appended := everyOpKind()
+	log, _, err := OpenOpLog(path)
+	assert.NoError(t, err)
+	assert.NoError(t, log.Append(appended[0]))
+	assert.NoError(t, log.Close())
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	record := encodeLoggedOp(appended[1])
+	appendToFile(t, path, append([]byte{0, 0, 1, 0, 0, 0, 0, 0}, record[:10]...))
+	log, ops, err := OpenOpLog(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(ops))
+	truncated, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, info.Size(), truncated.Size())
+	assert.NoError(t, log.Append(appended[1]))
+	assert.NoError(t, log.Close())
+	log, ops, err = OpenOpLog(path)
+	assert.NoError(t, err)
+	defer log.Close()
+	assert.Equal(t, 2, len(ops))
+	assertSameOp(t, appended[1], ops[1])
+}
+
+func TestShouldStopAtCorruptedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ops.log")
+	appended := everyOpKind()
+	log, _, err := OpenOpLog(path)
+	assert.NoError(t, err)
+	for _, op := range appended[:3] {
+		assert.NoError(t, log.Append(op))
+	}
+	assert.NoError(t, log.Close())
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	assert.NoError(t, os.WriteFile(path, data, 0o644))
+	log, ops, err := OpenOpLog(path)
+	assert.NoError(t, err)
+	defer log.Close()
+	assert.Equal(t, 2, len(ops))
+}
+
+func TestShouldRestoreCRDTFromLog(t *testing.T) {
+	LogMembershipChanges = false
+	path := filepath.Join(t.TempDir(), "ops.log")
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	assert.NoError(t, crdt.AttachLog(path))
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 30)), []*hashgraph.OpNode{firstNode})
+	add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(30, 40)), []*hashgraph.OpNode{add1Node})
+	postNode := hashgraph.NewNode(crdt.Post(ids[2], "msg"), []*hashgraph.OpNode{add2Node})
+	hashgraph.NewNode(crdt.Rem(ids[1], ids[2]), []*hashgraph.OpNode{postNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	crdt.Clear()
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, crdt.DetachLog())
+
+	restored := NewCRDT()
+	assert.NoError(t, restored.AttachLog(path))
+	defer restored.DetachLog()
+	assert.Equal(t, 5, len(restored.GetOperationList()))
+	restoredApp, err := ExecuteCRDT(&restored, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "", app.Diff(restoredApp))
+	assert.Equal(t, 2, len(restoredApp.users))
+}
+
+func TestShouldLogOpsDeliveredBeforeAttaching(t *testing.T) {
+	LogMembershipChanges = false
+	path := filepath.Join(t.TempDir(), "ops.log")
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 30)), []*hashgraph.OpNode{firstNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	assert.NoError(t, crdt.AttachLog(path))
+	assert.Error(t, crdt.AttachLog(path))
+	assert.NoError(t, crdt.DetachLog())
+	log, ops, err := OpenOpLog(path)
+	assert.NoError(t, err)
+	defer log.Close()
+	assert.Equal(t, 2, len(ops))
+}
+
+func TestShouldDecodeLoggedOps(t *testing.T) {
+	ops := everyOpKind()
+	assert.Equal(t, 24, len(ops))
+	for _, op := range ops {
+		encoded := encodeLoggedOp(op)
+		decoded, err := decodeLoggedOp(encoded)
+		assert.NoError(t, err)
+		assertSameOp(t, op, decoded)
+		assert.Equal(t, encoded, encodeLoggedOp(decoded))
+	}
+}
+
+func TestShouldRejectMalformedLoggedOps(t *testing.T) {
+	encoded := encodeLoggedOp(everyOpKind()[1])
+	for _, malformed := range [][]byte{
+		encoded[:len(encoded)-1],
+		append(encoded, 0),
+		append([]byte{opLogVersion + 1}, encoded[1:]...),
+		nil,
+	} {
+		_, err := decodeLoggedOp(malformed)
+		assert.Error(t, err)
+	}
+}
+
+func appendToFile(t *testing.T, path string, data []byte) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	assert.NoError(t, err)
+	_, err = file.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+}
+
+// everyOpKind returns signed operations of every kind, in the order they were executed.
+func everyOpKind() []*Op {
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	keys := genKeys(3, r)
+	for i := range ids {
+		crdt.SetKey(ids[i], keys[i])
+	}
+	// The encrypted deal needs the share keys of the owners, and the coin share a coin toss waiting for their values
+	other := NewCRDT()
+	otherFirst, _ := encryptedRemovalNodes(&other, ids[:2], keys[:2])
+	hashgraph.RunHashgraph(0, otherFirst)
+	view, err := ExecuteCRDT(&other, 100, 2)
+	if err != nil {
+		panic(err)
+	}
+	ops := []func(depth int, id uuid.UUID, prevIds []uuid.UUID) error{
+		crdt.Add(ids[0], ids[1], "B", makePtRange(0, 10)),
+		crdt.Post(ids[1], "msg"),
+		crdt.PostToChannel(ids[1], ids[2], "msg"),
+		crdt.Rem(ids[0], ids[1]),
+		crdt.ProposeRemoval(ids[0], ids[2]),
+		crdt.Vote(ids[1], ids[2], true),
+		crdt.Vote(ids[1], ids[2], false),
+		crdt.Transfer(ids[0], ids[1], makePtRange(10, 12)),
+		crdt.Leave(ids[1], nil),
+		crdt.Leave(ids[1], ids[:2]),
+		crdt.EditPost(ids[0], ids[1], "edit"),
+		crdt.DeletePost(ids[0], ids[1]),
+		crdt.Reply(ids[0], ids[1], "reply"),
+		crdt.React(ids[0], ids[1], "👍"),
+		crdt.Unreact(ids[0], ids[1], "👍"),
+		crdt.CreateChannel(ids[0], "channel"),
+		crdt.AddToChannel(ids[0], ids[1], ids[2], makePtRange(0, 3)),
+		crdt.GrantRole(ids[0], ids[1], RoleModerator),
+		crdt.RevokeRole(ids[0], ids[1]),
+		crdt.Unban(ids[0], ids[1]),
+		crdt.Deal(ids[0], ids[1], NewApp(100, 2)),
+		crdt.Deal(ids[0], ids[1], view),
+		crdt.RevealCoinShares(ids[0], view.PendingCoinShares()[0].Seed, view),
+	}
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], "A"), nil)
+	last := firstNode
+	for _, op := range ops {
+		last = hashgraph.NewNode(op, []*hashgraph.OpNode{last})
+	}
+	hashgraph.RunHashgraph(0, firstNode)
+	return crdt.GetOperationList()
+}
+
+func assertSameOp(t *testing.T, expected, actual *Op) {
+	assert.Equal(t, expected.idx, actual.idx)
+	assert.Equal(t, expected.kind, actual.kind)
+	assert.Equal(t, expected.id, actual.id)
+	assert.Equal(t, expected.prevIds, actual.prevIds)
+	assert.Equal(t, expected.sig, actual.sig)
+	assert.Equal(t, expected.payload(), actual.payload())
+}
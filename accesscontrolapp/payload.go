@@ -0,0 +1,603 @@
+package accesscontrolapp
+
+import (
+	"crypto/ed25519"
+	"dare_randomized_access_control/cointoss"
+	"encoding/binary"
+	"fmt"
+	"github.com/cloudflare/circl/group"
+	"github.com/cloudflare/circl/secretsharing"
+	"github.com/cloudflare/circl/zk/dleq"
+	. "github.com/google/uuid"
+	"github.com/negrel/assert"
+	"github.com/samber/lo"
+)
+
+// The payload of an operation is a canonical encoding of its type and content.
+// It is used to derive content-addressed operation ids and is what issuers sign.
+
+func InitPayload(initial UUID, prettyName string, pubKey ed25519.PublicKey, shareKey group.Element) []byte {
+	return (&InitOp{initial: initial, prettyName: prettyName, pubKey: pubKey, shareKey: shareKey}).payload()
+}
+
+func PostPayload(poster UUID, msg string) []byte {
+	return ChannelPostPayload(poster, Nil, msg)
+}
+
+func ChannelPostPayload(poster, channel UUID, msg string) []byte {
+	return (&PostOp{poster: poster, channel: channel, msg: msg}).payload()
+}
+
+func CreateChannelPayload(issuer UUID, name string) []byte {
+	return (&CreateChannelOp{issuer: issuer, name: name}).payload()
+}
+
+func AddToChannelPayload(issuer, channel, added UUID, points []uint) []byte {
+	return (&AddToChannelOp{issuer: issuer, channel: channel, added: added, points: points}).payload()
+}
+
+func ReplyPayload(poster, parent UUID, msg string) []byte {
+	return (&ReplyOp{poster: poster, parent: parent, msg: msg}).payload()
+}
+
+func ReactPayload(issuer, post UUID, emoji string, remove bool) []byte {
+	return (&ReactOp{issuer: issuer, post: post, emoji: emoji, remove: remove}).payload()
+}
+
+func EditPostPayload(issuer, post UUID, msg string) []byte {
+	return (&EditPostOp{issuer: issuer, post: post, msg: msg}).payload()
+}
+
+func DeletePostPayload(issuer, post UUID) []byte {
+	return (&DeletePostOp{issuer: issuer, post: post}).payload()
+}
+
+func AddPayload(issuer, added UUID, prettyName string, pubKey ed25519.PublicKey, shareKey group.Element, points []uint) []byte {
+	return (&AddOp{issuer: issuer, added: added, points: points, prettyName: prettyName, pubKey: pubKey, shareKey: shareKey}).payload()
+}
+
+func RemPayload(issuer, removed UUID) []byte {
+	return (&RemOp{issuer: issuer, removed: removed}).payload()
+}
+
+func TransferPayload(issuer, recipient UUID, points []uint) []byte {
+	return (&TransferOp{issuer: issuer, recipient: recipient, points: points}).payload()
+}
+
+func LeavePayload(issuer UUID, heirs []UUID) []byte {
+	return (&LeaveOp{issuer: issuer, heirs: heirs}).payload()
+}
+
+func GrantRolePayload(issuer, user UUID, role Role) []byte {
+	return (&RoleOp{issuer: issuer, user: user, role: role}).payload()
+}
+
+func RevokeRolePayload(issuer, user UUID) []byte {
+	return (&RoleOp{issuer: issuer, user: user, role: RoleMember, revoke: true}).payload()
+}
+
+func ProposeRemovalPayload(issuer, target UUID) []byte {
+	return (&ProposeRemovalOp{issuer: issuer, target: target}).payload()
+}
+
+func VotePayload(issuer, proposal UUID, inFavour bool) []byte {
+	return (&VoteOp{issuer: issuer, proposal: proposal, inFavour: inFavour}).payload()
+}
+
+func UnbanPayload(issuer, user UUID) []byte {
+	return (&UnbanOp{issuer: issuer, user: user}).payload()
+}
+
+// payload encodes the content of the operation, which its issuer signs. Operations of no known kind have no payload.
+func (op *Op) payload() []byte {
+	switch content := op.content.(type) {
+	case *InitOp:
+		return content.payload()
+	case *PostOp:
+		return content.payload()
+	case *CreateChannelOp:
+		return content.payload()
+	case *AddToChannelOp:
+		return content.payload()
+	case *ReplyOp:
+		return content.payload()
+	case *ReactOp:
+		return content.payload()
+	case *EditPostOp:
+		return content.payload()
+	case *DeletePostOp:
+		return content.payload()
+	case *AddOp:
+		return content.payload()
+	case *TransferOp:
+		return content.payload()
+	case *LeaveOp:
+		return content.payload()
+	case *RoleOp:
+		return content.payload()
+	case *ProposeRemovalOp:
+		return content.payload()
+	case *VoteOp:
+		return content.payload()
+	case *UnbanOp:
+		return content.payload()
+	case *DealOp:
+		return content.payload()
+	case *CoinShareOp:
+		return content.payload()
+	case *RemOp:
+		return content.payload()
+	default:
+		return nil
+	}
+}
+
+func (op *InitOp) payload() []byte {
+	b := []byte{byte(Init)}
+	b = append(b, op.initial[:]...)
+	b = appendBytes(b, op.pubKey)
+	b = appendElement(b, op.shareKey)
+	return appendString(b, op.prettyName)
+}
+
+func (op *PostOp) payload() []byte {
+	b := []byte{byte(Post)}
+	b = append(b, op.poster[:]...)
+	b = append(b, op.channel[:]...)
+	return appendString(b, op.msg)
+}
+
+func (op *CreateChannelOp) payload() []byte {
+	b := []byte{byte(CreateChannel)}
+	b = append(b, op.issuer[:]...)
+	return appendString(b, op.name)
+}
+
+func (op *AddToChannelOp) payload() []byte {
+	b := []byte{byte(AddToChannel)}
+	b = append(b, op.issuer[:]...)
+	b = append(b, op.channel[:]...)
+	b = append(b, op.added[:]...)
+	return appendPoints(b, op.points)
+}
+
+func (op *ReplyOp) payload() []byte {
+	b := []byte{byte(Reply)}
+	b = append(b, op.poster[:]...)
+	b = append(b, op.parent[:]...)
+	return appendString(b, op.msg)
+}
+
+func (op *ReactOp) payload() []byte {
+	b := []byte{byte(React)}
+	b = append(b, op.issuer[:]...)
+	b = append(b, op.post[:]...)
+	b = appendString(b, op.emoji)
+	if op.remove {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+func (op *EditPostOp) payload() []byte {
+	b := []byte{byte(EditPost)}
+	b = append(b, op.issuer[:]...)
+	b = append(b, op.post[:]...)
+	return appendString(b, op.msg)
+}
+
+func (op *DeletePostOp) payload() []byte {
+	b := []byte{byte(DeletePost)}
+	b = append(b, op.issuer[:]...)
+	return append(b, op.post[:]...)
+}
+
+func (op *AddOp) payload() []byte {
+	b := []byte{byte(Add)}
+	b = append(b, op.issuer[:]...)
+	b = append(b, op.added[:]...)
+	b = appendPoints(b, op.points)
+	b = appendBytes(b, op.pubKey)
+	b = appendElement(b, op.shareKey)
+	return appendString(b, op.prettyName)
+}
+
+func (op *RemOp) payload() []byte {
+	b := []byte{byte(Rem)}
+	b = append(b, op.issuer[:]...)
+	return append(b, op.removed[:]...)
+}
+
+func (op *TransferOp) payload() []byte {
+	b := []byte{byte(Transfer)}
+	b = append(b, op.issuer[:]...)
+	b = append(b, op.recipient[:]...)
+	return appendPoints(b, op.points)
+}
+
+func (op *LeaveOp) payload() []byte {
+	b := []byte{byte(Leave)}
+	b = append(b, op.issuer[:]...)
+	return appendIds(b, op.heirs)
+}
+
+func (op *RoleOp) payload() []byte {
+	b := []byte{byte(lo.Ternary(op.revoke, RevokeRole, GrantRole))}
+	b = append(b, op.issuer[:]...)
+	b = append(b, op.user[:]...)
+	return append(b, byte(op.role))
+}
+
+func (op *ProposeRemovalOp) payload() []byte {
+	b := []byte{byte(ProposeRemoval)}
+	b = append(b, op.issuer[:]...)
+	return append(b, op.target[:]...)
+}
+
+func (op *VoteOp) payload() []byte {
+	b := []byte{byte(Vote)}
+	b = append(b, op.issuer[:]...)
+	b = append(b, op.proposal[:]...)
+	if op.inFavour {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+func (op *UnbanOp) payload() []byte {
+	b := []byte{byte(Unban)}
+	b = append(b, op.issuer[:]...)
+	return append(b, op.user[:]...)
+}
+
+// payload of the deal. The ids of the values dealt are the positions of their points, starting from 1. Each value is
+// preceded by whether it is encrypted, in which case the owner, the ephemeral key and the masked value follow.
+func (op *DealOp) payload() []byte {
+	if op.encoded == nil {
+		op.encoded = op.encode()
+	}
+	return op.encoded
+}
+
+func (op *DealOp) encode() []byte {
+	b := []byte{byte(Deal)}
+	b = append(b, op.issuer[:]...)
+	b = append(b, op.refresh[:]...)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(op.commitment)))
+	for _, elem := range op.commitment {
+		b = appendElement(b, elem)
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(op.deltaVals)))
+	for i, val := range op.deltaVals {
+		if op.encDeltaVals == nil || op.encDeltaVals[i] == nil {
+			b = append(b, 0)
+			b = appendScalar(b, val.Value)
+			continue
+		}
+		enc := op.encDeltaVals[i]
+		b = append(b, 1)
+		b = append(b, enc.owner[:]...)
+		b = appendElement(b, enc.share.Ephemeral)
+		b = appendScalar(b, enc.share.Masked)
+	}
+	return b
+}
+
+// payload of the coin shares. The ids of the values are the positions of their points, starting from 1.
+func (op *CoinShareOp) payload() []byte {
+	if op.encoded == nil {
+		op.encoded = op.encode()
+	}
+	return op.encoded
+}
+
+func (op *CoinShareOp) encode() []byte {
+	b := []byte{byte(CoinShare)}
+	b = append(b, op.issuer[:]...)
+	b = binary.BigEndian.AppendUint64(b, uint64(op.seed))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(op.shares)))
+	for _, share := range op.shares {
+		b = append(b, share.deal[:]...)
+		b = binary.BigEndian.AppendUint32(b, uint32(share.point))
+		b = appendElement(b, share.share.Point)
+		proofBytes, err := share.share.Proof.MarshalBinary()
+		assert.NoError(err, "proofs over ristretto255 can always be encoded")
+		b = appendBytes(b, proofBytes)
+	}
+	return b
+}
+
+func appendPoints(b []byte, points []uint) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(points)))
+	for _, p := range points {
+		b = binary.BigEndian.AppendUint32(b, uint32(p))
+	}
+	return b
+}
+
+func appendIds(b []byte, ids []UUID) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(ids)))
+	for _, id := range ids {
+		b = append(b, id[:]...)
+	}
+	return b
+}
+
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+// appendElement appends the encoding of a group element, or an empty value if there is none.
+func appendElement(b []byte, elem group.Element) []byte {
+	if elem == nil {
+		return appendBytes(b, nil)
+	}
+	elemBytes, err := elem.MarshalBinary()
+	assert.NoError(err, "ristretto255 elements can always be encoded")
+	return appendBytes(b, elemBytes)
+}
+
+func appendScalar(b []byte, scalar group.Scalar) []byte {
+	scalarBytes, err := scalar.MarshalBinary()
+	assert.NoError(err, "ristretto255 scalars can always be encoded")
+	return appendBytes(b, scalarBytes)
+}
+
+func appendBytes(b []byte, val []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(val)))
+	return append(b, val...)
+}
+
+// decodePayload recovers the type and content of an operation from its payload.
+func decodePayload(payload []byte) (OpType, interface{}, error) {
+	d := &decoder{b: payload}
+	kind := OpType(d.byte())
+	var content interface{}
+	switch kind {
+	case Init:
+		content = &InitOp{initial: d.uuid(), pubKey: d.pubKey(), shareKey: d.element(), prettyName: d.string()}
+	case Post:
+		content = &PostOp{poster: d.uuid(), channel: d.uuid(), msg: d.string()}
+	case CreateChannel:
+		content = &CreateChannelOp{issuer: d.uuid(), name: d.string()}
+	case AddToChannel:
+		content = &AddToChannelOp{issuer: d.uuid(), channel: d.uuid(), added: d.uuid(), points: d.points()}
+	case Reply:
+		content = &ReplyOp{poster: d.uuid(), parent: d.uuid(), msg: d.string()}
+	case React:
+		content = &ReactOp{issuer: d.uuid(), post: d.uuid(), emoji: d.string(), remove: d.bool()}
+	case EditPost:
+		content = &EditPostOp{issuer: d.uuid(), post: d.uuid(), msg: d.string()}
+	case DeletePost:
+		content = &DeletePostOp{issuer: d.uuid(), post: d.uuid()}
+	case Add:
+		content = &AddOp{issuer: d.uuid(), added: d.uuid(), points: d.points(), pubKey: d.pubKey(), shareKey: d.element(), prettyName: d.string()}
+	case Rem:
+		content = &RemOp{issuer: d.uuid(), removed: d.uuid()}
+	case Transfer:
+		content = &TransferOp{issuer: d.uuid(), recipient: d.uuid(), points: d.points()}
+	case Leave:
+		issuer, heirs := d.uuid(), d.ids()
+		content = &LeaveOp{issuer: issuer, heirs: lo.Ternary(len(heirs) == 0, nil, heirs)}
+	case GrantRole, RevokeRole:
+		change := &RoleOp{issuer: d.uuid(), user: d.uuid(), role: Role(d.byte()), revoke: kind == RevokeRole}
+		if d.err == nil && change.role > RoleOwner {
+			d.err = fmt.Errorf("unknown role %d", change.role)
+		} else if d.err == nil && change.revoke && change.role != RoleMember {
+			d.err = fmt.Errorf("revoking a role must return the user to the member role")
+		}
+		content = change
+	case ProposeRemoval:
+		content = &ProposeRemovalOp{issuer: d.uuid(), target: d.uuid()}
+	case Vote:
+		content = &VoteOp{issuer: d.uuid(), proposal: d.uuid(), inFavour: d.bool()}
+	case Unban:
+		content = &UnbanOp{issuer: d.uuid(), user: d.uuid()}
+	case Deal:
+		deal := &DealOp{issuer: d.uuid(), refresh: d.uuid(), commitment: d.commitment()}
+		deal.deltaVals, deal.encDeltaVals = d.values()
+		content = deal
+	case CoinShare:
+		content = &CoinShareOp{issuer: d.uuid(), seed: int64(d.uint64()), shares: d.coinShares()}
+	default:
+		if d.err == nil {
+			return kind, nil, fmt.Errorf("unknown operation type %d", kind)
+		}
+	}
+	if err := d.done(); err != nil {
+		return kind, nil, fmt.Errorf("unable to decode payload of operation type %d: %v", kind, err)
+	}
+	return kind, content, nil
+}
+
+// decoder reads values in the encoding of the payloads, keeping the first error found.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	} else if n < 0 || len(d.b) < n {
+		d.err = fmt.Errorf("unexpected end of encoding")
+		return nil
+	}
+	val := d.b[:n]
+	d.b = d.b[n:]
+	return val
+}
+
+// done checks that every byte was decoded.
+func (d *decoder) done() error {
+	if d.err == nil && len(d.b) > 0 {
+		d.err = fmt.Errorf("%d unexpected trailing bytes", len(d.b))
+	}
+	return d.err
+}
+
+func (d *decoder) byte() byte {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) bool() bool {
+	v := d.byte()
+	if v > 1 && d.err == nil {
+		d.err = fmt.Errorf("invalid boolean %d", v)
+	}
+	return v == 1
+}
+
+func (d *decoder) uint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) uuid() UUID {
+	var id UUID
+	copy(id[:], d.next(len(id)))
+	return id
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uint32()
+	return append([]byte{}, d.next(int(n))...)
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) pubKey() ed25519.PublicKey {
+	key := d.bytes()
+	return lo.Ternary(len(key) == 0, nil, ed25519.PublicKey(key))
+}
+
+// element decodes a group element, or nil if the encoding is empty.
+func (d *decoder) element() group.Element {
+	elemBytes := d.bytes()
+	if len(elemBytes) == 0 || d.err != nil {
+		return nil
+	}
+	elem := group.Ristretto255.NewElement()
+	if err := elem.UnmarshalBinary(elemBytes); err != nil {
+		d.err = fmt.Errorf("invalid group element: %v", err)
+		return nil
+	}
+	return elem
+}
+
+// count decodes the number of values that follow.
+func (d *decoder) count() int {
+	return int(d.uint32())
+}
+
+func (d *decoder) scalar() group.Scalar {
+	scalarBytes := d.bytes()
+	if d.err != nil {
+		return nil
+	}
+	scalar := group.Ristretto255.NewScalar()
+	if err := scalar.UnmarshalBinary(scalarBytes); err != nil {
+		d.err = fmt.Errorf("invalid scalar: %v", err)
+		return nil
+	}
+	return scalar
+}
+
+func (d *decoder) commitment() secretsharing.SecretCommitment {
+	n := d.count()
+	commitment := make(secretsharing.SecretCommitment, 0, min(n, len(d.b)/4))
+	for i := 0; i < n && d.err == nil; i++ {
+		elem := d.element()
+		if elem == nil && d.err == nil {
+			d.err = fmt.Errorf("commitment to a coefficient is missing")
+		}
+		commitment = append(commitment, elem)
+	}
+	return commitment
+}
+
+// values decodes dealt values, whose ids are their positions starting from 1, and those encrypted, which are nil if
+// all are in plaintext.
+func (d *decoder) values() ([]secretsharing.Share, []*encryptedDelta) {
+	n := d.count()
+	values := make([]secretsharing.Share, 0, min(n, len(d.b)/4))
+	encrypted := make([]*encryptedDelta, 0, min(n, len(d.b)/4))
+	for i := 0; i < n && d.err == nil; i++ {
+		id := cointoss.NewScalar(uint64(i + 1))
+		if !d.bool() {
+			values = append(values, secretsharing.Share{ID: id, Value: d.scalar()})
+			encrypted = append(encrypted, nil)
+			continue
+		}
+		enc := &encryptedDelta{owner: d.uuid(), share: cointoss.EncryptedShare{ID: id, Ephemeral: d.element(), Masked: d.scalar()}}
+		if enc.share.Ephemeral == nil && d.err == nil {
+			d.err = fmt.Errorf("ephemeral key of encrypted value is missing")
+		}
+		values = append(values, secretsharing.Share{ID: id})
+		encrypted = append(encrypted, enc)
+	}
+	if lo.EveryBy(encrypted, func(enc *encryptedDelta) bool { return enc == nil }) {
+		return values, nil
+	}
+	return values, encrypted
+}
+
+// coinShares decodes the values published for a coin toss, whose ids are the positions of their points starting from 1.
+func (d *decoder) coinShares() []*coinShare {
+	n := d.count()
+	shares := make([]*coinShare, 0, min(n, len(d.b)/24))
+	for i := 0; i < n && d.err == nil; i++ {
+		deal, point, elem, proof := d.uuid(), d.uint32(), d.element(), d.proof()
+		if elem == nil && d.err == nil {
+			d.err = fmt.Errorf("published value is missing")
+		}
+		pointShare := cointoss.NewPointShare(cointoss.NewScalar(uint64(point)+1), elem)
+		shares = append(shares, &coinShare{deal: deal, point: uint(point), share: cointoss.VerifiablePointShare{PointShare: pointShare, Proof: proof}})
+	}
+	return shares
+}
+
+func (d *decoder) proof() *dleq.Proof {
+	proofBytes := d.bytes()
+	if d.err != nil {
+		return nil
+	}
+	proof := &dleq.Proof{}
+	if err := proof.UnmarshalBinary(group.Ristretto255, proofBytes); err != nil {
+		d.err = fmt.Errorf("invalid proof: %v", err)
+		return nil
+	}
+	return proof
+}
+
+func (d *decoder) ids() []UUID {
+	n := int(d.uint32())
+	ids := make([]UUID, 0, min(n, len(d.b)/16))
+	for i := 0; i < n && d.err == nil; i++ {
+		ids = append(ids, d.uuid())
+	}
+	return ids
+}
+
+func (d *decoder) points() []uint {
+	n := int(d.uint32())
+	points := make([]uint, 0, min(n, len(d.b)/4))
+	for i := 0; i < n && d.err == nil; i++ {
+		points = append(points, uint(d.uint32()))
+	}
+	return points
+}
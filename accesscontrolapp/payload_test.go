@@ -0,0 +1,85 @@
+package accesscontrolapp
+
+import (
+	"crypto/ed25519"
+	"dare_randomized_access_control/cointoss"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPayloadsShouldBeDeterministic(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	assert.Equal(t, AddPayload(a, b, "B", nil, nil, []uint{1, 2}), AddPayload(a, b, "B", nil, nil, []uint{1, 2}))
+	assert.Equal(t, PostPayload(a, "msg"), PostPayload(a, "msg"))
+}
+
+func TestPayloadsShouldDifferWithContent(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	shareKey := cointoss.DeriveShareKey([]byte("seed"))
+	payloads := [][]byte{
+		InitPayload(a, "A", nil, nil),
+		InitPayload(a, "B", nil, nil),
+		InitPayload(a, "B", make(ed25519.PublicKey, ed25519.PublicKeySize), nil),
+		InitPayload(a, "B", nil, shareKey.Public),
+		PostPayload(a, "A"),
+		PostPayload(b, "A"),
+		ChannelPostPayload(a, b, "A"),
+		CreateChannelPayload(a, "A"),
+		CreateChannelPayload(a, "B"),
+		AddToChannelPayload(a, b, b, []uint{1}),
+		AddToChannelPayload(a, b, a, []uint{1}),
+		ReplyPayload(a, b, "A"),
+		ReplyPayload(b, a, "A"),
+		ReactPayload(a, b, "A", false),
+		ReactPayload(a, b, "A", true),
+		ReactPayload(a, b, "B", false),
+		EditPostPayload(a, b, "A"),
+		EditPostPayload(a, b, "B"),
+		EditPostPayload(b, a, "A"),
+		DeletePostPayload(a, b),
+		DeletePostPayload(b, a),
+		AddPayload(a, b, "B", nil, nil, []uint{1, 2}),
+		AddPayload(a, b, "B", nil, nil, []uint{1, 3}),
+		AddPayload(a, b, "B", nil, nil, []uint{1}),
+		AddPayload(a, b, "B", make(ed25519.PublicKey, ed25519.PublicKeySize), nil, []uint{1}),
+		AddPayload(a, b, "B", nil, shareKey.Public, []uint{1}),
+		AddPayload(b, a, "B", nil, nil, []uint{1, 2}),
+		RemPayload(a, b),
+		RemPayload(b, a),
+		TransferPayload(a, b, []uint{1, 2}),
+		TransferPayload(a, b, []uint{1, 3}),
+		TransferPayload(b, a, []uint{1, 2}),
+		LeavePayload(a, nil),
+		LeavePayload(a, []uuid.UUID{b}),
+		LeavePayload(b, []uuid.UUID{a}),
+		ProposeRemovalPayload(a, b),
+		ProposeRemovalPayload(b, a),
+		VotePayload(a, b, true),
+		VotePayload(a, b, false),
+		VotePayload(b, a, true),
+		GrantRolePayload(a, b, RoleModerator),
+		GrantRolePayload(a, b, RoleOwner),
+		GrantRolePayload(b, a, RoleModerator),
+		RevokeRolePayload(a, b),
+		RevokeRolePayload(b, a),
+		UnbanPayload(a, b),
+		UnbanPayload(b, a),
+	}
+	for i := range payloads {
+		for j := range payloads {
+			if i != j {
+				assert.NotEqual(t, payloads[i], payloads[j])
+			}
+		}
+	}
+}
+
+func TestShouldNotPanicOnUnknownContent(t *testing.T) {
+	op := &Op{content: &ConflictResolutionOp{}}
+	assert.Nil(t, op.payload())
+	assert.Equal(t, uuid.Nil, op.issuer())
+	valid, rejection := NewApp(10, 2).hasValidSignature(op, make(ed25519.PublicKey, ed25519.PublicKeySize))
+	assert.False(t, valid)
+	assert.Equal(t, ReasonInvalidSignature, rejection.Code)
+}
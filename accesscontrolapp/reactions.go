@@ -0,0 +1,100 @@
+package accesscontrolapp
+
+import (
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"log/slog"
+)
+
+// The reactions to a post form an observed-remove set. Each reaction added is tagged with the id of its operation.
+// Removing a reaction removes only the tags of the issuer's reactions in its causal past, so a reaction added
+// concurrently with a removal survives it.
+
+// reactions to a post, mapping each emoji to the tags of the reactions with it and the users that added them.
+type reactions map[string]map[uuid.UUID]uuid.UUID
+
+func (app *App) react(op *Op) error {
+	app.graphNodes[op.id] = app.dummyBNode(op)
+	react := op.content.(*ReactOp)
+	if canReact, reason := app.canReact(op); !canReact {
+		return reason
+	}
+	postReactions := app.reactions[react.post]
+	if postReactions == nil {
+		postReactions = make(reactions)
+		app.reactions[react.post] = postReactions
+	}
+	tags := postReactions[react.emoji]
+	if tags == nil {
+		tags = make(map[uuid.UUID]uuid.UUID)
+		postReactions[react.emoji] = tags
+	}
+	if !react.remove {
+		tags[op.id] = react.issuer
+		slog.Debug("Reacted to message", "issuer", react.issuer, "post", react.post, "emoji", react.emoji)
+		return nil
+	}
+	issued := lo.PickByValues(tags, []uuid.UUID{react.issuer})
+	for tag := range app.observed(op, lo.Keys(issued)) {
+		delete(tags, tag)
+	}
+	slog.Debug("Removed reaction to message", "issuer", react.issuer, "post", react.post, "emoji", react.emoji)
+	return nil
+}
+
+func (app *App) canReact(op *Op) (bool, *Rejection) {
+	react := op.content.(*ReactOp)
+	if !app.hasPrevious(op) {
+		return false, reject(ReasonMissingPrevious, "previous operation ids do not exist")
+	} else if len(op.prevIds) == 0 {
+		return false, reject(ReasonNoPrevious, "react operation must have at least one previous operation")
+	}
+	issuer := app.users[react.issuer]
+	msg := app.GetMsg(react.post)
+	if issuer == nil {
+		return false, reject(ReasonNotAMember, "operation issuer is not a user")
+	} else if valid, reason := app.hasValidSignature(op, issuer.pubKey); !valid {
+		return false, reason
+	} else if msg == nil {
+		return false, reject(ReasonUnknownTarget, "post does not exist")
+	} else if msg.Deleted {
+		return false, reject(ReasonDeleted, "post has been deleted")
+	} else if !app.canAccess(react.issuer, msg.Channel) {
+		return false, reject(ReasonNotInChannel, "issuer is not a member of the channel")
+	} else if react.emoji == "" {
+		return false, reject(ReasonInvalidContent, "reaction must have an emoji")
+	}
+	return true, nil
+}
+
+// observed returns the operations among those given that are in the causal past of the operation.
+func (app *App) observed(op *Op, ids []uuid.UUID) map[uuid.UUID]bool {
+	pending := lo.SliceToMap(ids, func(id uuid.UUID) (uuid.UUID, bool) { return id, true })
+	found := make(map[uuid.UUID]bool)
+	visited := make(map[uuid.UUID]bool)
+	queue := lo.Map(op.prevIds, func(id uuid.UUID, _ int) *backnode { return app.graphNodes[id] })
+	for len(queue) > 0 && len(found) < len(pending) {
+		node := queue[0]
+		queue = queue[1:]
+		if visited[node.id] {
+			continue
+		}
+		visited[node.id] = true
+		if pending[node.id] {
+			found[node.id] = true
+		}
+		queue = append(queue, node.prev...)
+	}
+	return found
+}
+
+// ReactionCounts returns the number of users reacting to the post with each emoji.
+func (app *App) ReactionCounts(post uuid.UUID) map[string]int {
+	counts := make(map[string]int)
+	for emoji, tags := range app.reactions[post] {
+		if users := len(lo.Uniq(lo.Values(tags))); users > 0 {
+			counts[emoji] = users
+		}
+	}
+	return counts
+}
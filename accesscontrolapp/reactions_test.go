@@ -0,0 +1,74 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/hashgraph"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldCountReactions(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 10)
+	postNode := hashgraph.NewNode(crdt.Post(ids[0], "msg"), []*hashgraph.OpNode{addNode})
+	react1Node := hashgraph.NewNode(crdt.React(ids[1], postNode.GetId(), "👍"), []*hashgraph.OpNode{postNode})
+	hashgraph.NewNode(crdt.React(ids[1], postNode.GetId(), "👍"), []*hashgraph.OpNode{react1Node})
+	hashgraph.NewNode(crdt.React(ids[2], postNode.GetId(), "👍"), []*hashgraph.OpNode{postNode})
+	hashgraph.NewNode(crdt.React(ids[2], postNode.GetId(), "🎉"), []*hashgraph.OpNode{postNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"👍": 2, "🎉": 1}, app.ReactionCounts(postNode.GetId()))
+}
+
+func TestShouldRemoveObservedReactions(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 10)
+	postNode := hashgraph.NewNode(crdt.Post(ids[0], "msg"), []*hashgraph.OpNode{addNode})
+	reactNode := hashgraph.NewNode(crdt.React(ids[1], postNode.GetId(), "👍"), []*hashgraph.OpNode{postNode})
+	hashgraph.NewNode(crdt.React(ids[0], postNode.GetId(), "👍"), []*hashgraph.OpNode{postNode})
+	hashgraph.NewNode(crdt.Unreact(ids[1], postNode.GetId(), "👍"), []*hashgraph.OpNode{reactNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"👍": 1}, app.ReactionCounts(postNode.GetId()))
+}
+
+func TestConcurrentReactionShouldSurviveRemoval(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 10)
+	postNode := hashgraph.NewNode(crdt.Post(ids[0], "msg"), []*hashgraph.OpNode{addNode})
+	reactNode := hashgraph.NewNode(crdt.React(ids[1], postNode.GetId(), "👍"), []*hashgraph.OpNode{postNode})
+	hashgraph.NewNode(crdt.Unreact(ids[1], postNode.GetId(), "👍"), []*hashgraph.OpNode{reactNode})
+	concurrentNode := hashgraph.NewNode(crdt.Post(ids[1], "other"), []*hashgraph.OpNode{postNode})
+	hashgraph.NewNode(crdt.React(ids[1], postNode.GetId(), "👍"), []*hashgraph.OpNode{concurrentNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"👍": 1}, app.ReactionCounts(postNode.GetId()))
+}
+
+func TestShouldDropReactionsOfDeletedPost(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(1, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	postNode := hashgraph.NewNode(crdt.Post(ids[0], "msg"), []*hashgraph.OpNode{firstNode})
+	reactNode := hashgraph.NewNode(crdt.React(ids[0], postNode.GetId(), "👍"), []*hashgraph.OpNode{postNode})
+	deleteNode := hashgraph.NewNode(crdt.DeletePost(ids[0], postNode.GetId()), []*hashgraph.OpNode{reactNode})
+	hashgraph.NewNode(crdt.React(ids[0], postNode.GetId(), "🎉"), []*hashgraph.OpNode{deleteNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, app.ReactionCounts(postNode.GetId()))
+}
@@ -0,0 +1,136 @@
+package accesscontrolapp
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"slices"
+)
+
+// Operations that cannot be applied are rejected, and executing them leaves the state unchanged. Apps keep a report of
+// the operations they rejected, with the reason of each rejection and its causal context.
+// Reasons are Rejection errors. Their codes are machine-readable and errors.Is matches a rejection against its code.
+
+type ReasonCode string
+
+const (
+	// ReasonMissingPrevious rejects operations following operations that were not delivered
+	ReasonMissingPrevious ReasonCode = "missing-previous"
+	// ReasonNoPrevious rejects operations that must follow at least one operation
+	ReasonNoPrevious ReasonCode = "no-previous"
+	// ReasonNotAMember rejects operations whose issuer is not a member of the group
+	ReasonNotAMember ReasonCode = "not-a-member"
+	// ReasonUnknownUser rejects operations targeting a user who is not a member of the group
+	ReasonUnknownUser ReasonCode = "unknown-user"
+	// ReasonSelfTarget rejects operations whose issuer targets themselves where they may not
+	ReasonSelfTarget ReasonCode = "self-target"
+	ReasonReadOnly   ReasonCode = "read-only"
+	// ReasonNotPermitted rejects operations the role or points of the issuer do not allow
+	ReasonNotPermitted ReasonCode = "not-permitted"
+	ReasonBanned       ReasonCode = "banned"
+	ReasonNotBanned    ReasonCode = "not-banned"
+	// ReasonInvalidPoints rejects operations giving no points or points the issuer cannot give
+	ReasonInvalidPoints ReasonCode = "invalid-points"
+	ReasonAlreadyMember ReasonCode = "already-member"
+	ReasonLastMember    ReasonCode = "last-member"
+	// ReasonNotInChannel rejects operations whose issuer is not a member of the channel they act on
+	ReasonNotInChannel ReasonCode = "not-in-channel"
+	// ReasonUnknownTarget rejects operations acting on a post, channel or proposal that does not exist
+	ReasonUnknownTarget ReasonCode = "unknown-target"
+	ReasonDeleted       ReasonCode = "deleted"
+	ReasonDecided       ReasonCode = "decided"
+	// ReasonInvalidContent rejects operations whose content is malformed
+	ReasonInvalidContent   ReasonCode = "invalid-content"
+	ReasonInvalidSignature ReasonCode = "invalid-signature"
+	// ReasonInvalidDeal rejects operations whose dealt values do not match their commitment
+	ReasonInvalidDeal ReasonCode = "invalid-deal"
+	// ReasonOverridden rejects removals and role changes overridden by concurrent ones
+	ReasonOverridden ReasonCode = "overridden"
+	// ReasonCoinToss rejects conflicting operations whose coin toss could not be computed
+	ReasonCoinToss ReasonCode = "coin-toss"
+	// ReasonUnknown rejects operations failing with errors that are not rejections
+	ReasonUnknown ReasonCode = "unknown"
+)
+
+func (c ReasonCode) Error() string {
+	return string(c)
+}
+
+// Rejection is the reason an operation was rejected.
+type Rejection struct {
+	Code   ReasonCode
+	Reason string
+	// Cause is the id of the operation leading to the rejection, uuid.Nil if there is none
+	Cause uuid.UUID
+}
+
+func reject(code ReasonCode, format string, args ...interface{}) *Rejection {
+	return &Rejection{Code: code, Reason: fmt.Sprintf(format, args...)}
+}
+
+// causedBy sets the operation leading to the rejection.
+func (r *Rejection) causedBy(cause uuid.UUID) *Rejection {
+	r.Cause = cause
+	return r
+}
+
+func (r *Rejection) Error() string {
+	return r.Reason
+}
+
+func (r *Rejection) Unwrap() error {
+	return r.Code
+}
+
+// RejectedOp reports a rejected operation along with its causal context.
+type RejectedOp struct {
+	Id        uuid.UUID
+	Kind      OpType
+	Issuer    uuid.UUID
+	Idx       int64
+	PrevIds   []uuid.UUID
+	Rejection *Rejection
+}
+
+// Rejected returns the operations rejected, in the order they were executed.
+func (app *App) Rejected() []RejectedOp {
+	return slices.Clone(app.rejections)
+}
+
+// RejectedBy returns the operations of the issuer that were rejected, in the order they were executed.
+func (app *App) RejectedBy(issuer uuid.UUID) []RejectedOp {
+	rejected := make([]RejectedOp, 0)
+	for _, op := range app.rejections {
+		if op.Issuer == issuer {
+			rejected = append(rejected, op)
+		}
+	}
+	return rejected
+}
+
+// Rejection returns the reason the operation was rejected, if it was.
+func (app *App) Rejection(id uuid.UUID) (*Rejection, bool) {
+	for _, op := range app.rejections {
+		if op.Id == id {
+			return op.Rejection, true
+		}
+	}
+	return nil, false
+}
+
+// rejected records that the operation was not applied and notifies the observers.
+func (app *App) rejected(op *Op, err error) {
+	var rejection *Rejection
+	if !errors.As(err, &rejection) {
+		rejection = reject(ReasonUnknown, "%v", err)
+	}
+	app.rejections = append(app.rejections, RejectedOp{
+		Id:        op.id,
+		Kind:      op.kind,
+		Issuer:    op.issuer(),
+		Idx:       op.idx,
+		PrevIds:   slices.Clone(op.prevIds),
+		Rejection: rejection,
+	})
+	app.emit(OpRejected{Op: op.id, Kind: op.kind, Issuer: op.issuer(), Err: rejection})
+}
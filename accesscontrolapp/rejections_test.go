@@ -0,0 +1,100 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/hashgraph"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldReportRejectedAddOfBannedUser(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	remNode := hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{addNode})
+	readdNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{remNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	rejected := app.Rejected()
+	assert.Equal(t, 1, len(rejected))
+	assert.Equal(t, readdNode.GetId(), rejected[0].Id)
+	assert.Equal(t, Add, rejected[0].Kind)
+	assert.Equal(t, ids[0], rejected[0].Issuer)
+	assert.Equal(t, []uuid.UUID{remNode.GetId()}, rejected[0].PrevIds)
+	assert.Equal(t, ReasonBanned, rejected[0].Rejection.Code)
+	assert.Equal(t, remNode.GetId(), rejected[0].Rejection.Cause)
+	assert.True(t, errors.Is(rejected[0].Rejection, ReasonBanned))
+	assert.False(t, errors.Is(rejected[0].Rejection, ReasonNotAMember))
+}
+
+func TestShouldQueryRejectedOps(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	postNode := hashgraph.NewNode(crdt.Post(ids[1], "msg"), []*hashgraph.OpNode{firstNode})
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(0, 200)), []*hashgraph.OpNode{postNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(app.Rejected()))
+	byNonMember := app.RejectedBy(ids[1])
+	assert.Equal(t, 1, len(byNonMember))
+	assert.Equal(t, postNode.GetId(), byNonMember[0].Id)
+	assert.Equal(t, ReasonNotAMember, byNonMember[0].Rejection.Code)
+	assert.Equal(t, uuid.Nil, byNonMember[0].Rejection.Cause)
+	rejection, ok := app.Rejection(addNode.GetId())
+	assert.True(t, ok)
+	assert.Equal(t, ReasonInvalidPoints, rejection.Code)
+	_, ok = app.Rejection(firstNode.GetId())
+	assert.False(t, ok)
+	assert.Equal(t, 0, len(app.RejectedBy(ids[2])))
+}
+
+func TestShouldReportOverriddenRoleChange(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 45)), []*hashgraph.OpNode{firstNode})
+	add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(45, 55)), []*hashgraph.OpNode{add1Node})
+	deal := committeeDealNode(&crdt, ids, add2Node, NewApp(100, 2))
+	modNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[1], RoleModerator), []*hashgraph.OpNode{deal})
+	grantNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[2], RoleModerator), []*hashgraph.OpNode{modNode})
+	restrictNode := hashgraph.NewNode(crdt.GrantRole(ids[1], ids[2], RoleReadOnly), []*hashgraph.OpNode{modNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	rejected := app.Rejected()
+	assert.Equal(t, 1, len(rejected))
+	assert.Equal(t, ReasonOverridden, rejected[0].Rejection.Code)
+	winner := map[uuid.UUID]uuid.UUID{grantNode.GetId(): restrictNode.GetId(), restrictNode.GetId(): grantNode.GetId()}
+	assert.Equal(t, winner[rejected[0].Id], rejected[0].Rejection.Cause)
+}
+
+func TestShouldKeepRejectedOpsInSnapshot(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	remNode := hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{addNode})
+	readdNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{remNode})
+	hashgraph.NewNode(crdt.Post(ids[2], "msg"), []*hashgraph.OpNode{readdNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	restored, err := decodeSnapshot(t, encodeSnapshot(t, app)).restore()
+	assert.NoError(t, err)
+	assert.Equal(t, app.Rejected(), restored.Rejected())
+	assert.True(t, errors.Is(restored.Rejected()[0].Rejection, ReasonBanned))
+}
@@ -0,0 +1,214 @@
+package accesscontrolapp
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/negrel/assert"
+	"github.com/samber/lo"
+	"log/slog"
+)
+
+// Roles restrict what members may do, on top of the points they hold. The creator of the group is its first owner
+// and users join as members, reaching other roles only through role changes. Read-only users cannot post, add or
+// remove users. Moderators and owners change the roles of users below them, granting roles up to their own, and owners
+// change the roles of anyone but themselves.
+// Role changes targeting the same user conflict if they are concurrent and lead to different roles. Those at the same
+// depth are resolved with a single coin toss, drawing the change that prevails with probability proportional to its
+// issuer's points. A role change concurrent with the one that set the current role of the user, at a lower depth, is
+// drawn against it in the same way, and only applied if it prevails.
+
+type Role byte
+
+const (
+	RoleReadOnly Role = iota
+	RoleMember
+	RoleModerator
+	RoleOwner
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleReadOnly:
+		return "read-only"
+	case RoleMember:
+		return "member"
+	case RoleModerator:
+		return "moderator"
+	case RoleOwner:
+		return "owner"
+	default:
+		return fmt.Sprintf("role(%d)", byte(r))
+	}
+}
+
+// roleChange identifies the role change that set the role of a user.
+type roleChange struct {
+	op     uuid.UUID
+	issuer uuid.UUID
+}
+
+func (u *User) Role() Role {
+	return u.role
+}
+
+// canWrite checks whether the user may post, change the membership of the group or the channels, vote on removals
+// and change the posts of others.
+func (u *User) canWrite() bool {
+	return u.role >= RoleMember
+}
+
+// concurrentRoleChanges returns the role changes at the same depth as the role change at position i of the operation list.
+func concurrentRoleChanges(opList []*Op, i int) []*Op {
+	assert.True(opList[i].kind == GrantRole || opList[i].kind == RevokeRole, "First operation must be a role change when this method is called")
+	end := i + 1
+	for end < len(opList) && (opList[end].kind == GrantRole || opList[end].kind == RevokeRole) && opList[end].depth() == opList[i].depth() {
+		end++
+	}
+	return opList[i:end]
+}
+
+// changeRolesConcurrent executes role changes at the same depth, resolving those leading the same user to different roles.
+func (app *App) changeRolesConcurrent(ops []*Op) {
+	byUser := lo.GroupBy(ops, func(op *Op) uuid.UUID { return op.content.(*RoleOp).user })
+	users := lo.Uniq(lo.Map(ops, func(op *Op, _ int) uuid.UUID { return op.content.(*RoleOp).user }))
+	for _, user := range users {
+		valid := make([]*Op, 0)
+		for _, op := range byUser[user] {
+			if canChange, reason := app.canChangeRole(op); !canChange {
+				app.graphNodes[op.id] = app.dummyBNode(op)
+				slog.Warn("Unable to compute role change", "err", reason, "idx", op.idx, "op", op.content.(*RoleOp))
+				app.rejected(op, reason)
+			} else {
+				valid = append(valid, op)
+			}
+		}
+		roles := lo.Uniq(lo.Map(valid, func(op *Op, _ int) Role { return op.content.(*RoleOp).role }))
+		if len(roles) > 1 {
+			winner, err := app.drawRoleChange(valid)
+			if err != nil {
+				slog.Warn("Unable to resolve concurrent role changes", "err", err, "idx", valid[0].idx)
+				rejection := reject(ReasonCoinToss, "%v", err)
+				lo.ForEach(valid, func(op *Op, _ int) {
+					app.graphNodes[op.id] = app.dummyBNode(op)
+					app.rejected(op, rejection)
+				})
+				continue
+			}
+			for _, op := range lo.Without(valid, winner) {
+				app.graphNodes[op.id] = app.dummyBNode(op)
+				slog.Debug("Role change overridden by a concurrent role change", "idx", op.idx)
+				app.rejected(op, reject(ReasonOverridden, "role change overridden by a concurrent role change").causedBy(winner.id))
+			}
+			valid = []*Op{winner}
+		}
+		if len(valid) > 0 && !app.prevailsOverEarlierChange(valid) {
+			continue
+		}
+		lo.ForEach(valid, func(op *Op, _ int) { app.changeRole(op) })
+	}
+}
+
+// prevailsOverEarlierChange checks whether the role changes, which lead their user to the same role, prevail over the
+// role change that set the current role of the user. They do if it comes before all of them, and otherwise if their
+// issuers are drawn before its issuer. Changes that do not prevail are rejected.
+func (app *App) prevailsOverEarlierChange(ops []*Op) bool {
+	change := ops[0].content.(*RoleOp)
+	user := app.users[change.user]
+	earlier := user.roleChange
+	if earlier == nil || user.role == change.role || app.users[earlier.issuer] == nil {
+		return true
+	} else if lo.EveryBy(ops, func(op *Op) bool { return app.precedes(earlier.op, op) }) {
+		return true
+	}
+	issuers := lo.Uniq(append(lo.Map(ops, func(op *Op, _ int) uuid.UUID { return op.issuer() }), earlier.issuer))
+	order, _, err := app.drawOrder(issuers, ops[0].idx)
+	if err != nil {
+		slog.Warn("Unable to resolve role change concurrent with an earlier one", "err", err, "idx", ops[0].idx)
+		rejection := reject(ReasonCoinToss, "%v", err)
+		lo.ForEach(ops, func(op *Op, _ int) {
+			app.graphNodes[op.id] = app.dummyBNode(op)
+			app.rejected(op, rejection)
+		})
+		return false
+	} else if order[0] != earlier.issuer || lo.SomeBy(ops, func(op *Op) bool { return op.issuer() == earlier.issuer }) {
+		return true
+	}
+	for _, op := range ops {
+		app.graphNodes[op.id] = app.dummyBNode(op)
+		slog.Debug("Role change overridden by an earlier concurrent role change", "idx", op.idx)
+		app.rejected(op, reject(ReasonOverridden, "role change overridden by a concurrent role change").causedBy(earlier.op))
+	}
+	return false
+}
+
+// precedes checks whether the operation with the id is in the causal history of the operation.
+func (app *App) precedes(id uuid.UUID, op *Op) bool {
+	visited := make(map[uuid.UUID]bool)
+	pending := lo.FilterMap(op.prevIds, func(prevId uuid.UUID, _ int) (*backnode, bool) {
+		node, found := app.graphNodes[prevId]
+		return node, found
+	})
+	for len(pending) > 0 {
+		node := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if node == nil || visited[node.id] {
+			continue
+		} else if node.id == id {
+			return true
+		}
+		visited[node.id] = true
+		pending = append(pending, node.prev...)
+	}
+	return false
+}
+
+// drawRoleChange draws the role change that prevails among conflicting ones, weighting their issuers by their points.
+func (app *App) drawRoleChange(ops []*Op) (*Op, error) {
+	issuers, _, err := app.drawIssuerOrder(ops)
+	if err != nil {
+		return nil, err
+	}
+	winner, _ := lo.Find(ops, func(op *Op) bool { return op.issuer() == issuers[0] })
+	return winner, nil
+}
+
+func (app *App) changeRole(op *Op) {
+	app.graphNodes[op.id] = app.dummyBNode(op)
+	change := op.content.(*RoleOp)
+	user := app.users[change.user]
+	user.role = change.role
+	user.roleChange = &roleChange{op: op.id, issuer: change.issuer}
+	slog.Debug("Changed role", "issuer", change.issuer, "user", change.user, "role", change.role)
+	if LogMembershipChanges {
+		app.Msgs = append(app.Msgs, Msg{Issuer: change.issuer, Content: createControlMsgf(cyan, "%s made %s %s", app.users[change.issuer].prettyName, user.prettyName, change.role)})
+	}
+}
+
+func (app *App) canChangeRole(op *Op) (bool, *Rejection) {
+	change := op.content.(*RoleOp)
+	if !app.hasPrevious(op) {
+		return false, reject(ReasonMissingPrevious, "previous operation ids do not exist")
+	} else if len(op.prevIds) == 0 {
+		return false, reject(ReasonNoPrevious, "role change must have at least one previous operation")
+	} else if change.issuer == change.user {
+		return false, reject(ReasonSelfTarget, "user cannot change their own role")
+	} else if change.role > RoleOwner {
+		return false, reject(ReasonInvalidContent, "unknown role")
+	}
+	issuer := app.users[change.issuer]
+	user := app.users[change.user]
+	if issuer == nil {
+		return false, reject(ReasonNotAMember, "operation issuer is not a user")
+	} else if user == nil {
+		return false, reject(ReasonUnknownUser, "user is not in the system")
+	} else if valid, reason := app.hasValidSignature(op, issuer.pubKey); !valid {
+		return false, reason
+	} else if issuer.role < RoleModerator {
+		return false, reject(ReasonNotPermitted, "only moderators and owners can change roles")
+	} else if issuer.role != RoleOwner && user.role >= issuer.role {
+		return false, reject(ReasonNotPermitted, "issuer cannot change the role of users with a role equal or above their own")
+	} else if change.role > issuer.role {
+		return false, reject(ReasonNotPermitted, "issuer cannot grant a role above their own")
+	}
+	return true, nil
+}
@@ -0,0 +1,231 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/hashgraph"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldAddUsersAsMembers(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	grantNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[1], RoleModerator), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Add(ids[1], ids[2], "", makePtRange(0, 5)), []*hashgraph.OpNode{grantNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleOwner, app.users[ids[0]].Role())
+	assert.Equal(t, RoleModerator, app.users[ids[1]].Role())
+	assert.Equal(t, RoleMember, app.users[ids[2]].Role())
+}
+
+func TestShouldRevokeRole(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	grantNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[1], RoleModerator), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.RevokeRole(ids[0], ids[1]), []*hashgraph.OpNode{grantNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleMember, app.users[ids[1]].Role())
+}
+
+func TestShouldPreventReadOnlyUsersFromWriting(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	grantNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[1], RoleReadOnly), []*hashgraph.OpNode{addNode})
+	postNode := hashgraph.NewNode(crdt.Post(ids[1], "msg"), []*hashgraph.OpNode{grantNode})
+	addNode2 := hashgraph.NewNode(crdt.Add(ids[1], ids[2], "", makePtRange(0, 5)), []*hashgraph.OpNode{postNode})
+	hashgraph.NewNode(crdt.Rem(ids[1], ids[0]), []*hashgraph.OpNode{addNode2})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, app.Msgs)
+	assert.Equal(t, 2, len(app.users))
+	assert.NotNil(t, app.users[ids[0]])
+	assert.Equal(t, 10, app.users[ids[1]].Points.Len())
+}
+
+func TestShouldRestrictRoleChangesOfModerators(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(10, 20)), []*hashgraph.OpNode{add1Node})
+	grant1Node := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[1], RoleModerator), []*hashgraph.OpNode{add2Node})
+	grant2Node := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[2], RoleMember), []*hashgraph.OpNode{grant1Node})
+	aboveNode := hashgraph.NewNode(crdt.GrantRole(ids[1], ids[2], RoleOwner), []*hashgraph.OpNode{grant2Node})
+	ownerNode := hashgraph.NewNode(crdt.RevokeRole(ids[1], ids[0]), []*hashgraph.OpNode{aboveNode})
+	selfNode := hashgraph.NewNode(crdt.GrantRole(ids[1], ids[1], RoleOwner), []*hashgraph.OpNode{ownerNode})
+	hashgraph.NewNode(crdt.GrantRole(ids[1], ids[2], RoleReadOnly), []*hashgraph.OpNode{selfNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleOwner, app.users[ids[0]].Role())
+	assert.Equal(t, RoleModerator, app.users[ids[1]].Role())
+	assert.Equal(t, RoleReadOnly, app.users[ids[2]].Role())
+}
+
+func TestShouldPreventMembersFromChangingRoles(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	grantNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[1], RoleMember), []*hashgraph.OpNode{add1Node})
+	add2Node := hashgraph.NewNode(crdt.Add(ids[1], ids[2], "", makePtRange(0, 5)), []*hashgraph.OpNode{grantNode})
+	hashgraph.NewNode(crdt.GrantRole(ids[1], ids[2], RoleReadOnly), []*hashgraph.OpNode{add2Node})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleMember, app.users[ids[2]].Role())
+}
+
+func TestShouldResolveConflictingRoleChangesEitherWay(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	wins := make(map[Role]int)
+	for i := 0; i < 40; i++ {
+		crdt := NewCRDT()
+		ids := genIds(3, r)
+		firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+		add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 45)), []*hashgraph.OpNode{firstNode})
+		add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(45, 55)), []*hashgraph.OpNode{add1Node})
+		deal := committeeDealNode(&crdt, ids, add2Node, NewApp(100, 2))
+		modNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[1], RoleModerator), []*hashgraph.OpNode{deal})
+		hashgraph.NewNode(crdt.GrantRole(ids[0], ids[2], RoleModerator), []*hashgraph.OpNode{modNode})
+		hashgraph.NewNode(crdt.GrantRole(ids[1], ids[2], RoleReadOnly), []*hashgraph.OpNode{modNode})
+		hashgraph.RunHashgraph(0, firstNode)
+		app, err := ExecuteCRDT(&crdt, 100, 2)
+		assert.NoError(t, err)
+		again, err := ExecuteCRDT(&crdt, 100, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, app.users[ids[2]].Role(), again.users[ids[2]].Role())
+		wins[app.users[ids[2]].Role()]++
+	}
+	assert.Greater(t, wins[RoleModerator], 0)
+	assert.Greater(t, wins[RoleReadOnly], 0)
+	assert.Equal(t, 40, wins[RoleModerator]+wins[RoleReadOnly])
+}
+
+func TestShouldResolveRoleChangesConcurrentAtDifferentDepths(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	wins := make(map[Role]int)
+	for i := 0; i < 40; i++ {
+		crdt := NewCRDT()
+		ids := genIds(3, r)
+		firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+		add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 45)), []*hashgraph.OpNode{firstNode})
+		add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(45, 55)), []*hashgraph.OpNode{add1Node})
+		deal := committeeDealNode(&crdt, ids, add2Node, NewApp(100, 2))
+		modNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[1], RoleOwner), []*hashgraph.OpNode{deal})
+		grantNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[2], RoleModerator), []*hashgraph.OpNode{modNode})
+		postNode := hashgraph.NewNode(crdt.Post(ids[1], "msg"), []*hashgraph.OpNode{modNode})
+		restrictNode := hashgraph.NewNode(crdt.GrantRole(ids[1], ids[2], RoleReadOnly), []*hashgraph.OpNode{postNode})
+		hashgraph.NewNode(crdt.Post(ids[0], "msg"), []*hashgraph.OpNode{grantNode, restrictNode})
+		hashgraph.RunHashgraph(0, firstNode)
+		app, err := ExecuteCRDT(&crdt, 100, 2)
+		assert.NoError(t, err)
+		again, err := ExecuteCRDT(&crdt, 100, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, app.users[ids[2]].Role(), again.users[ids[2]].Role())
+		wins[app.users[ids[2]].Role()]++
+		if app.users[ids[2]].Role() == RoleModerator {
+			rejected := app.Rejected()
+			assert.Equal(t, 1, len(rejected))
+			assert.Equal(t, restrictNode.GetId(), rejected[0].Id)
+			assert.Equal(t, ReasonOverridden, rejected[0].Rejection.Code)
+			assert.Equal(t, grantNode.GetId(), rejected[0].Rejection.Cause)
+		} else {
+			assert.Empty(t, app.Rejected())
+		}
+	}
+	assert.Greater(t, wins[RoleModerator], 0)
+	assert.Greater(t, wins[RoleReadOnly], 0)
+	assert.Equal(t, 40, wins[RoleModerator]+wins[RoleReadOnly])
+}
+
+func TestShouldApplyRoleChangesAfterEarlierOnes(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 45)), []*hashgraph.OpNode{firstNode})
+	add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(45, 55)), []*hashgraph.OpNode{add1Node})
+	modNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[1], RoleModerator), []*hashgraph.OpNode{add2Node})
+	grantNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[2], RoleModerator), []*hashgraph.OpNode{modNode})
+	revokeNode := hashgraph.NewNode(crdt.RevokeRole(ids[0], ids[2]), []*hashgraph.OpNode{grantNode})
+	hashgraph.NewNode(crdt.GrantRole(ids[1], ids[2], RoleReadOnly), []*hashgraph.OpNode{revokeNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleReadOnly, app.users[ids[2]].Role())
+	assert.Empty(t, app.Rejected())
+}
+
+func TestShouldKeepRoleChangesInSnapshot(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 45)), []*hashgraph.OpNode{firstNode})
+	grantNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[1], RoleModerator), []*hashgraph.OpNode{addNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	restored, err := decodeSnapshot(t, encodeSnapshot(t, app)).restore()
+	assert.NoError(t, err)
+	assert.Equal(t, &roleChange{op: grantNode.GetId(), issuer: ids[0]}, restored.users[ids[1]].roleChange)
+	assert.Nil(t, restored.users[ids[0]].roleChange)
+}
+
+func TestShouldApplyConcurrentMatchingRoleChanges(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(10, 20)), []*hashgraph.OpNode{add1Node})
+	hashgraph.NewNode(crdt.GrantRole(ids[0], ids[2], RoleReadOnly), []*hashgraph.OpNode{add2Node})
+	hashgraph.NewNode(crdt.GrantRole(ids[1], ids[2], RoleReadOnly), []*hashgraph.OpNode{add2Node})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleReadOnly, app.users[ids[2]].Role())
+}
+
+func TestShouldLogRoleChange(t *testing.T) {
+	LogMembershipChanges = true
+	defer func() { LogMembershipChanges = false }()
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], "A"), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "B", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	hashgraph.NewNode(crdt.GrantRole(ids[0], ids[1], RoleModerator), []*hashgraph.OpNode{addNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(app.Msgs))
+	assert.Contains(t, app.Msgs[2].Content, "A made B moderator")
+}
@@ -0,0 +1,65 @@
+package accesscontrolapp
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	. "github.com/google/uuid"
+)
+
+// SetKey registers the private key of a user. Operations issued by the user through this CRDT are signed with it,
+// and the user's public key is included in the operations adding them to the group.
+// The user's share key is derived from it.
+func (crdt *CRDT) SetKey(user UUID, key ed25519.PrivateKey) {
+	crdt.keys[user] = key
+	crdt.pubKeys[user] = key.Public().(ed25519.PublicKey)
+	crdt.SetShareKey(user, ShareKeyFromSigningKey(key))
+}
+
+// SetPublicKey registers the public key included in the operations adding the user to the group.
+func (crdt *CRDT) SetPublicKey(user UUID, key ed25519.PublicKey) {
+	crdt.pubKeys[user] = key
+}
+
+// AddSignature records the signature of an operation issued elsewhere, to be attached to it once it is delivered.
+func (crdt *CRDT) AddSignature(opId UUID, sig []byte) {
+	crdt.signatures[opId] = sig
+}
+
+func (crdt *CRDT) signature(op *Op, issuer UUID) []byte {
+	if sig := crdt.signatures[op.id]; sig != nil {
+		return sig
+	} else if key := crdt.keys[issuer]; key != nil {
+		return SignOp(key, op.payload(), op.prevIds)
+	}
+	return nil
+}
+
+// SignOp signs the payload of an operation together with the ids of the operations it comes after.
+func SignOp(key ed25519.PrivateKey, payload []byte, prevIds []UUID) []byte {
+	return ed25519.Sign(key, signingBytes(payload, prevIds))
+}
+
+func signingBytes(payload []byte, prevIds []UUID) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	b = append(b, payload...)
+	for _, id := range prevIds {
+		b = append(b, id[:]...)
+	}
+	return b
+}
+
+func (app *App) hasValidSignature(op *Op, pubKey ed25519.PublicKey) (bool, *Rejection) {
+	if len(pubKey) == 0 {
+		if app.signed {
+			return false, reject(ReasonInvalidSignature, "operation issuer has no public key")
+		}
+		return true, nil
+	} else if len(pubKey) != ed25519.PublicKeySize {
+		return false, reject(ReasonInvalidSignature, "operation issuer has an invalid public key")
+	} else if op.sig == nil {
+		return false, reject(ReasonInvalidSignature, "operation is not signed")
+	} else if !ed25519.Verify(pubKey, signingBytes(op.payload(), op.prevIds), op.sig) {
+		return false, reject(ReasonInvalidSignature, "invalid operation signature")
+	}
+	return true, nil
+}
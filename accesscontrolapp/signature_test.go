@@ -0,0 +1,138 @@
+package accesscontrolapp
+
+import (
+	"crypto/ed25519"
+	"dare_randomized_access_control/hashgraph"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldAcceptSignedOperations(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	keys := genKeys(2, r)
+	crdt.SetKey(ids[0], keys[0])
+	crdt.SetKey(ids[1], keys[1])
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 50)), []*hashgraph.OpNode{firstNode})
+	hashgraph.NewNode(crdt.Post(ids[1], "signed"), []*hashgraph.OpNode{addNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(app.users))
+	assert.Equal(t, keys[1].Public(), app.users[ids[1]].pubKey)
+	assert.Equal(t, 1, len(app.Msgs))
+}
+
+func TestShouldRejectForgedRemoval(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	keys := genKeys(2, r)
+	crdt.SetKey(ids[0], keys[0])
+	crdt.SetKey(ids[1], keys[1])
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 90)), []*hashgraph.OpNode{firstNode})
+	forgedId := uuid.New()
+	// The second user signs a removal of themselves in the name of the first
+	crdt.AddSignature(forgedId, SignOp(keys[1], RemPayload(ids[0], ids[1]), []uuid.UUID{addNode.GetId()}))
+	hashgraph.NewNodeWithId(forgedId, crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{addNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(app.users))
+}
+
+func TestShouldRejectSignatureOverOtherPredecessors(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(1, r)
+	keys := genKeys(1, r)
+	crdt.SetKey(ids[0], keys[0])
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	replayedId := uuid.New()
+	crdt.AddSignature(replayedId, SignOp(keys[0], PostPayload(ids[0], "replayed"), []uuid.UUID{uuid.New()}))
+	hashgraph.NewNodeWithId(replayedId, crdt.Post(ids[0], "replayed"), []*hashgraph.OpNode{firstNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(app.Msgs))
+}
+
+func TestShouldRejectUnsignedOperationFromUserWithKey(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	keys := genKeys(2, r)
+	crdt.SetKey(ids[0], keys[0])
+	crdt.SetPublicKey(ids[1], keys[1].Public().(ed25519.PublicKey))
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 50)), []*hashgraph.OpNode{firstNode})
+	hashgraph.NewNode(crdt.Post(ids[1], "unsigned"), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Add(ids[1], uuid.New(), "", makePtRange(0, 10)), []*hashgraph.OpNode{addNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(app.users))
+	assert.Equal(t, 0, len(app.Msgs))
+}
+
+func TestShouldRequireSignatures(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	keys := genKeys(1, r)
+	crdt.SetKey(ids[0], keys[0])
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 50)), []*hashgraph.OpNode{firstNode})
+	hashgraph.NewNode(crdt.Post(ids[1], "keyless"), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Post(ids[0], "signed"), []*hashgraph.OpNode{addNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(app.users))
+	assert.Equal(t, 1, len(app.Msgs))
+	assert.Equal(t, "signed", app.Msgs[0].Content)
+}
+
+func TestShouldRequireSignaturesAfterResuming(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	keys := genKeys(1, r)
+	crdt.SetKey(ids[0], keys[0])
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 50)), []*hashgraph.OpNode{firstNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	snapshot := decodeSnapshot(t, encodeSnapshot(t, app))
+	hashgraph.NewNode(crdt.Post(ids[1], "keyless"), []*hashgraph.OpNode{addNode})
+	crdt.Clear()
+	hashgraph.RunHashgraph(0, firstNode)
+	resumed, err := ExecuteCRDTFrom(snapshot, &crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(resumed.Msgs))
+	assert.Equal(t, ReasonInvalidSignature, resumed.Rejected()[0].Rejection.Code)
+}
+
+func genKeys(num int, r *rand.Rand) []ed25519.PrivateKey {
+	keys := make([]ed25519.PrivateKey, 0, num)
+	for i := 0; i < num; i++ {
+		_, key, err := ed25519.GenerateKey(r)
+		if err != nil {
+			panic(err)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,588 @@
+package accesscontrolapp
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"dare_randomized_access_control/cointoss"
+	"fmt"
+	"github.com/cloudflare/circl/group"
+	"github.com/cloudflare/circl/secretsharing"
+	"github.com/google/uuid"
+	"github.com/petar/GoLLRB/llrb"
+	"github.com/samber/lo"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+)
+
+// A snapshot holds the state of an app after executing a prefix of the operation list, so that execution can resume
+// from it instead of from Init. Besides the state, it records the number of operations executed and a digest chaining
+// their ids, which tell whether the snapshot is still a prefix of a later operation list. It is not if an operation
+// was delivered since with an idx below that of the last operation executed.
+// Snapshots are encoded in JSON.
+
+// SnapshotVersion is the version of the encoding of snapshots.
+const SnapshotVersion = 1
+
+type Snapshot struct {
+	Version int `json:"version"`
+	// Idx of the last operation executed
+	Idx       int64           `json:"idx"`
+	Applied   int             `json:"applied"`
+	Digest    []byte          `json:"digest"`
+	Signed    bool            `json:"signed"`
+	NumPoints int             `json:"numPoints"`
+	Threshold int             `json:"threshold"`
+	Users     []userState     `json:"users"`
+	Msgs      []Msg           `json:"msgs"`
+	Reactions []reactionState `json:"reactions"`
+	Channels  []channelState  `json:"channels"`
+	Proposals []proposalState `json:"proposals"`
+	Bans      []banState      `json:"bans"`
+	Rejected  []rejectedState `json:"rejected"`
+	// Refreshes are listed in the order they were opened
+	Refreshes []refreshState `json:"refreshes"`
+	Deals     []uuid.UUID    `json:"deals"`
+	// PendingCoins holds the coin tosses lacking values, which the coin shares following them may decide
+	PendingCoins []pendingCoinState `json:"pendingCoins"`
+	GraphNodes   []graphNodeState   `json:"graphNodes"`
+}
+
+type userState struct {
+	Id         uuid.UUID `json:"id"`
+	PrettyName string    `json:"prettyName"`
+	PubKey     []byte    `json:"pubKey"`
+	ShareKey   []byte    `json:"shareKey"`
+	Role       Role      `json:"role"`
+	// RoleChange is the id of the role change that set the role, or uuid.Nil if the user joined with it
+	RoleChange       uuid.UUID `json:"roleChange"`
+	RoleChangeIssuer uuid.UUID `json:"roleChangeIssuer"`
+	Points           []uint    `json:"points"`
+}
+
+type reactionState struct {
+	Post  uuid.UUID `json:"post"`
+	Emoji string    `json:"emoji"`
+	Tag   uuid.UUID `json:"tag"`
+	User  uuid.UUID `json:"user"`
+}
+
+type channelState struct {
+	Id      uuid.UUID            `json:"id"`
+	Name    string               `json:"name"`
+	Members []channelMemberState `json:"members"`
+}
+
+type channelMemberState struct {
+	User   uuid.UUID `json:"user"`
+	Points []uint    `json:"points"`
+}
+
+type proposalState struct {
+	Id       uuid.UUID          `json:"id"`
+	Proposer uuid.UUID          `json:"proposer"`
+	Target   uuid.UUID          `json:"target"`
+	Votes    map[uuid.UUID]bool `json:"votes"`
+	Decided  bool               `json:"decided"`
+}
+
+type banState struct {
+	User       uuid.UUID   `json:"user"`
+	RemovedBy  uuid.UUID   `json:"removedBy"`
+	PrettyName string      `json:"prettyName"`
+	Approvals  []uuid.UUID `json:"approvals"`
+}
+
+type rejectedState struct {
+	Id      uuid.UUID   `json:"id"`
+	Kind    OpType      `json:"kind"`
+	Issuer  uuid.UUID   `json:"issuer"`
+	Idx     int64       `json:"idx"`
+	PrevIds []uuid.UUID `json:"prevIds"`
+	Code    ReasonCode  `json:"code"`
+	Reason  string      `json:"reason"`
+	Cause   uuid.UUID   `json:"cause"`
+}
+
+type refreshState struct {
+	Id        uuid.UUID               `json:"id"`
+	Committee []uuid.UUID             `json:"committee"`
+	Dealt     map[uuid.UUID]uuid.UUID `json:"dealt"`
+}
+
+type pendingCoinState struct {
+	Seed  int64       `json:"seed"`
+	Deals []uuid.UUID `json:"deals"`
+}
+
+type graphNodeState struct {
+	Id        uuid.UUID    `json:"id"`
+	DeltaVals []shareState `json:"deltaVals"`
+	// EncDeltaVals holds null for the deltaVals left in plaintext
+	EncDeltaVals []*encryptedDeltaState `json:"encDeltaVals"`
+	Commitment   [][]byte               `json:"commitment"`
+	// Prev holds the ids of the previous nodes, with uuid.Nil for those missing
+	Prev []uuid.UUID `json:"prev"`
+}
+
+type shareState struct {
+	Id    []byte `json:"id"`
+	Value []byte `json:"value"`
+}
+
+type encryptedDeltaState struct {
+	Owner     uuid.UUID `json:"owner"`
+	Id        []byte    `json:"id"`
+	Ephemeral []byte    `json:"ephemeral"`
+	Masked    []byte    `json:"masked"`
+}
+
+// ExecuteOpListUntil executes the operations with idx up to until, along with those resolved together with the last
+// of them. The resulting app can be snapshot at that point of the operation list.
+func ExecuteOpListUntil(opList []*Op, until int64, numPoints int, threshold int) (*App, error) {
+	app := NewApp(numPoints, threshold)
+	return app, app.execute(opList, until)
+}
+
+// ExecuteOpListFrom executes the operations, resuming from the snapshot if it was taken over a prefix of them with the
+// same number of points and threshold. Otherwise, or if there is no snapshot, the operations are executed from Init.
+func ExecuteOpListFrom(snapshot *Snapshot, opList []*Op, numPoints int, threshold int) (*App, error) {
+	if snapshot == nil || snapshot.NumPoints != numPoints || snapshot.Threshold != threshold || !snapshot.isPrefixOf(opList) {
+		slog.Debug("No snapshot of a prefix of the operations, executing from init")
+		return ExecuteOpList(opList, numPoints, threshold)
+	}
+	app, err := snapshot.restore()
+	if err != nil {
+		return nil, err
+	}
+	return app, app.execute(opList[snapshot.Applied:], math.MaxInt64)
+}
+
+// ExecuteCRDTFrom executes the operations of the CRDT, resuming from the snapshot like ExecuteOpListFrom.
+func ExecuteCRDTFrom(snapshot *Snapshot, crdt *CRDT, numPoints, threshold int) (*App, error) {
+	return ExecuteOpListFrom(snapshot, crdt.GetOperationList(), numPoints, threshold)
+}
+
+// isPrefixOf checks whether the operations executed before the snapshot are the first operations of the list, none
+// of the operations after them should have been resolved together with the last of them, and none of them publishes
+// values for a coin toss pending in the snapshot.
+func (s *Snapshot) isPrefixOf(opList []*Op) bool {
+	if s.Applied > len(opList) || (s.Applied > 0 && opList[s.Applied-1].idx != s.Idx) {
+		return false
+	} else if s.Applied > 0 && s.Applied < len(opList) && resolvedTogether(opList[s.Applied-1], opList[s.Applied]) {
+		return false
+	} else if lo.SomeBy(opList[s.Applied:], s.decidesPendingCoin) {
+		return false
+	}
+	digest := make([]byte, 0)
+	for _, op := range opList[:s.Applied] {
+		digest = chainDigest(digest, op)
+	}
+	return bytes.Equal(digest, s.Digest)
+}
+
+// decidesPendingCoin checks whether the operation publishes values for a coin toss pending in the snapshot.
+func (s *Snapshot) decidesPendingCoin(op *Op) bool {
+	reveal, ok := op.content.(*CoinShareOp)
+	return ok && lo.SomeBy(s.PendingCoins, func(state pendingCoinState) bool { return state.Seed == reveal.seed })
+}
+
+// resolvedTogether checks whether the operations are resolved together, as concurrent removals or role changes.
+func resolvedTogether(op, next *Op) bool {
+	isRoleChange := func(op *Op) bool { return op.kind == GrantRole || op.kind == RevokeRole }
+	return op.depth() == next.depth() && ((op.kind == Rem && next.kind == Rem) || (isRoleChange(op) && isRoleChange(next)))
+}
+
+// record accounts for the operations once executed.
+func (app *App) record(ops []*Op) {
+	for _, op := range ops {
+		app.digest = chainDigest(app.digest, op)
+		app.lastIdx = op.idx
+		app.applied++
+	}
+}
+
+func chainDigest(digest []byte, op *Op) []byte {
+	h := sha256.New()
+	h.Write(digest)
+	h.Write(op.id[:])
+	return h.Sum(nil)
+}
+
+// Snapshot captures the state of the app after the operations executed so far.
+func (app *App) Snapshot() (*Snapshot, error) {
+	users, err := mapErr(app.Members(), userSnapshot)
+	if err != nil {
+		return nil, err
+	}
+	graphNodes, err := mapErr(sortedValues(app.graphNodes, func(n *backnode) uuid.UUID { return n.id }), graphNodeSnapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{
+		Version:      SnapshotVersion,
+		Idx:          app.lastIdx,
+		Applied:      app.applied,
+		Digest:       slices.Clone(app.digest),
+		Signed:       app.signed,
+		NumPoints:    app.numPoints,
+		Threshold:    app.threshold,
+		Users:        users,
+		Msgs:         cloneMsgs(app.Msgs),
+		Reactions:    app.reactionsSnapshot(),
+		Channels:     lo.Map(app.Channels(), func(c *Channel, _ int) channelState { return channelSnapshot(c) }),
+		Proposals:    app.proposalsSnapshot(),
+		Bans:         app.bansSnapshot(),
+		Rejected:     lo.Map(app.rejections, func(r RejectedOp, _ int) rejectedState { return rejectedSnapshot(r) }),
+		Refreshes:    app.refreshesSnapshot(),
+		Deals:        slices.Clone(app.deals),
+		PendingCoins: app.pendingCoinsSnapshot(),
+		GraphNodes:   graphNodes,
+	}, nil
+}
+
+func userSnapshot(u *User) (userState, error) {
+	shareKey, err := marshalElement(u.shareKey)
+	state := userState{
+		Id:         u.Id,
+		PrettyName: u.prettyName,
+		PubKey:     u.pubKey,
+		ShareKey:   shareKey,
+		Role:       u.role,
+		Points:     u.PointList(),
+	}
+	if u.roleChange != nil {
+		state.RoleChange = u.roleChange.op
+		state.RoleChangeIssuer = u.roleChange.issuer
+	}
+	return state, err
+}
+
+func (app *App) reactionsSnapshot() []reactionState {
+	states := make([]reactionState, 0)
+	for post, postReactions := range app.reactions {
+		for emoji, tags := range postReactions {
+			for tag, user := range tags {
+				states = append(states, reactionState{Post: post, Emoji: emoji, Tag: tag, User: user})
+			}
+		}
+	}
+	slices.SortFunc(states, func(a, b reactionState) int { return compareIds(a.Tag, b.Tag) })
+	return states
+}
+
+func channelSnapshot(c *Channel) channelState {
+	return channelState{
+		Id:   c.Id,
+		Name: c.Name,
+		Members: lo.Map(c.Members(), func(member uuid.UUID, _ int) channelMemberState {
+			return channelMemberState{User: member, Points: pointList(c.members[member])}
+		}),
+	}
+}
+
+func (app *App) proposalsSnapshot() []proposalState {
+	states := lo.MapToSlice(app.proposals, func(id uuid.UUID, p *removalProposal) proposalState {
+		return proposalState{Id: id, Proposer: p.proposer, Target: p.target, Votes: maps.Clone(p.votes), Decided: p.decided}
+	})
+	slices.SortFunc(states, func(a, b proposalState) int { return compareIds(a.Id, b.Id) })
+	return states
+}
+
+func rejectedSnapshot(r RejectedOp) rejectedState {
+	return rejectedState{
+		Id:      r.Id,
+		Kind:    r.Kind,
+		Issuer:  r.Issuer,
+		Idx:     r.Idx,
+		PrevIds: slices.Clone(r.PrevIds),
+		Code:    r.Rejection.Code,
+		Reason:  r.Rejection.Reason,
+		Cause:   r.Rejection.Cause,
+	}
+}
+
+func (app *App) bansSnapshot() []banState {
+	return lo.Map(app.Banned(), func(user uuid.UUID, _ int) banState {
+		b := app.bans[user]
+		approvals := lo.Keys(b.approvals)
+		slices.SortFunc(approvals, compareIds)
+		return banState{User: user, RemovedBy: b.removedBy, PrettyName: b.prettyName, Approvals: approvals}
+	})
+}
+
+func (app *App) pendingCoinsSnapshot() []pendingCoinState {
+	states := lo.MapToSlice(app.pendingCoins, func(seed int64, deals []uuid.UUID) pendingCoinState {
+		return pendingCoinState{Seed: seed, Deals: slices.Clone(deals)}
+	})
+	slices.SortFunc(states, func(a, b pendingCoinState) int { return cmp.Compare(a.Seed, b.Seed) })
+	return states
+}
+
+func (app *App) refreshesSnapshot() []refreshState {
+	return lo.Map(app.refreshOrder, func(id uuid.UUID, _ int) refreshState {
+		r := app.refreshes[id]
+		return refreshState{Id: id, Committee: slices.Clone(r.committee), Dealt: maps.Clone(r.dealt)}
+	})
+}
+
+func graphNodeSnapshot(n *backnode) (graphNodeState, error) {
+	deltaVals, err := mapNilErr(n.deltaVals, shareSnapshot)
+	if err != nil {
+		return graphNodeState{}, err
+	}
+	encDeltaVals, err := mapNilErr(n.encDeltaVals, encryptedDeltaSnapshot)
+	if err != nil {
+		return graphNodeState{}, err
+	}
+	commitment, err := mapNilErr(n.commitment, marshalElement)
+	if err != nil {
+		return graphNodeState{}, err
+	}
+	return graphNodeState{
+		Id:           n.id,
+		DeltaVals:    deltaVals,
+		EncDeltaVals: encDeltaVals,
+		Commitment:   commitment,
+		Prev: lo.Map(n.prev, func(p *backnode, _ int) uuid.UUID {
+			if p == nil {
+				return uuid.Nil
+			}
+			return p.id
+		}),
+	}, nil
+}
+
+func shareSnapshot(share secretsharing.Share) (shareState, error) {
+	id, err := share.ID.MarshalBinary()
+	if err != nil {
+		return shareState{}, err
+	}
+	value, err := marshalScalar(share.Value)
+	return shareState{Id: id, Value: value}, err
+}
+
+func encryptedDeltaSnapshot(delta *encryptedDelta) (*encryptedDeltaState, error) {
+	if delta == nil {
+		return nil, nil
+	}
+	id, err := delta.share.ID.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := marshalElement(delta.share.Ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	masked, err := delta.share.Masked.MarshalBinary()
+	return &encryptedDeltaState{Owner: delta.owner, Id: id, Ephemeral: ephemeral, Masked: masked}, err
+}
+
+// restore rebuilds the app whose state the snapshot holds.
+func (s *Snapshot) restore() (*App, error) {
+	if s.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	app := NewApp(s.NumPoints, s.Threshold)
+	app.applied = s.Applied
+	app.lastIdx = s.Idx
+	app.digest = slices.Clone(s.Digest)
+	app.signed = s.Signed
+	for _, state := range s.Users {
+		user := newUser(state.Id, state.PrettyName, state.Points)
+		user.pubKey = lo.Ternary(len(state.PubKey) == 0, nil, state.PubKey)
+		user.role = state.Role
+		if state.RoleChange != uuid.Nil {
+			user.roleChange = &roleChange{op: state.RoleChange, issuer: state.RoleChangeIssuer}
+		}
+		shareKey, err := unmarshalElement(state.ShareKey)
+		if err != nil {
+			return nil, err
+		}
+		user.shareKey = shareKey
+		app.users[user.Id] = user
+	}
+	app.Msgs = cloneMsgs(s.Msgs)
+	for i, msg := range app.Msgs {
+		if msg.Id != uuid.Nil {
+			app.msgIdx[msg.Id] = i
+		}
+	}
+	for _, state := range s.Reactions {
+		postReactions := app.reactions[state.Post]
+		if postReactions == nil {
+			postReactions = make(reactions)
+			app.reactions[state.Post] = postReactions
+		}
+		if postReactions[state.Emoji] == nil {
+			postReactions[state.Emoji] = make(map[uuid.UUID]uuid.UUID)
+		}
+		postReactions[state.Emoji][state.Tag] = state.User
+	}
+	for _, state := range s.Channels {
+		members := lo.SliceToMap(state.Members, func(m channelMemberState) (uuid.UUID, *llrb.LLRB) { return m.User, newPointSet(m.Points) })
+		app.channels[state.Id] = &Channel{Id: state.Id, Name: state.Name, members: members}
+	}
+	for _, state := range s.Proposals {
+		votes := lo.Ternary(state.Votes == nil, make(map[uuid.UUID]bool), maps.Clone(state.Votes))
+		app.proposals[state.Id] = &removalProposal{proposer: state.Proposer, target: state.Target, votes: votes, decided: state.Decided}
+	}
+	for _, state := range s.Bans {
+		approvals := lo.SliceToMap(state.Approvals, func(id uuid.UUID) (uuid.UUID, bool) { return id, true })
+		app.bans[state.User] = &ban{removedBy: state.RemovedBy, prettyName: state.PrettyName, approvals: approvals}
+	}
+	app.rejections = lo.Map(s.Rejected, func(state rejectedState, _ int) RejectedOp {
+		return RejectedOp{
+			Id:        state.Id,
+			Kind:      state.Kind,
+			Issuer:    state.Issuer,
+			Idx:       state.Idx,
+			PrevIds:   slices.Clone(state.PrevIds),
+			Rejection: &Rejection{Code: state.Code, Reason: state.Reason, Cause: state.Cause},
+		}
+	})
+	for _, state := range s.Refreshes {
+		dealt := lo.Ternary(state.Dealt == nil, make(map[uuid.UUID]uuid.UUID), maps.Clone(state.Dealt))
+		app.refreshes[state.Id] = &refresh{committee: slices.Clone(state.Committee), dealt: dealt}
+		app.refreshOrder = append(app.refreshOrder, state.Id)
+	}
+	app.deals = append(app.deals, s.Deals...)
+	for _, state := range s.PendingCoins {
+		app.pendingCoins[state.Seed] = slices.Clone(state.Deals)
+	}
+	return app, app.restoreGraphNodes(s.GraphNodes)
+}
+
+func (app *App) restoreGraphNodes(states []graphNodeState) error {
+	for _, state := range states {
+		node, err := restoreGraphNode(state)
+		if err != nil {
+			return err
+		}
+		app.graphNodes[node.id] = node
+	}
+	for _, state := range states {
+		app.graphNodes[state.Id].prev = lo.Map(state.Prev, func(id uuid.UUID, _ int) *backnode { return app.graphNodes[id] })
+	}
+	return nil
+}
+
+func restoreGraphNode(state graphNodeState) (*backnode, error) {
+	deltaVals, err := mapNilErr(state.DeltaVals, restoreShare)
+	if err != nil {
+		return nil, err
+	}
+	encDeltaVals, err := mapNilErr(state.EncDeltaVals, restoreEncryptedDelta)
+	if err != nil {
+		return nil, err
+	}
+	commitment, err := mapNilErr(state.Commitment, unmarshalElement)
+	if err != nil {
+		return nil, err
+	}
+	return &backnode{
+		id:           state.Id,
+		deltaVals:    deltaVals,
+		encDeltaVals: encDeltaVals,
+		commitment:   commitment,
+	}, nil
+}
+
+func restoreShare(state shareState) (secretsharing.Share, error) {
+	id, err := unmarshalScalar(state.Id)
+	if err != nil {
+		return secretsharing.Share{}, err
+	}
+	value, err := unmarshalScalar(state.Value)
+	return secretsharing.Share{ID: id, Value: value}, err
+}
+
+func restoreEncryptedDelta(state *encryptedDeltaState) (*encryptedDelta, error) {
+	if state == nil {
+		return nil, nil
+	}
+	id, err := unmarshalScalar(state.Id)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := unmarshalElement(state.Ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	masked, err := unmarshalScalar(state.Masked)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedDelta{owner: state.Owner, share: cointoss.EncryptedShare{ID: id, Ephemeral: ephemeral, Masked: masked}}, nil
+}
+
+func cloneMsgs(msgs []Msg) []Msg {
+	return lo.Map(msgs, func(msg Msg, _ int) Msg {
+		msg.Edits = slices.Clone(msg.Edits)
+		return msg
+	})
+}
+
+func marshalElement(elem group.Element) ([]byte, error) {
+	if elem == nil {
+		return nil, nil
+	}
+	return elem.MarshalBinary()
+}
+
+func unmarshalElement(b []byte) (group.Element, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	elem := group.Ristretto255.NewElement()
+	if err := elem.UnmarshalBinary(b); err != nil {
+		return nil, fmt.Errorf("invalid group element in snapshot: %v", err)
+	}
+	return elem, nil
+}
+
+func marshalScalar(scalar group.Scalar) ([]byte, error) {
+	if scalar == nil {
+		return nil, nil
+	}
+	return scalar.MarshalBinary()
+}
+
+func unmarshalScalar(b []byte) (group.Scalar, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	scalar := group.Ristretto255.NewScalar()
+	if err := scalar.UnmarshalBinary(b); err != nil {
+		return nil, fmt.Errorf("invalid scalar in snapshot: %v", err)
+	}
+	return scalar, nil
+}
+
+func sortedValues[V any](m map[uuid.UUID]V, id func(V) uuid.UUID) []V {
+	values := lo.Values(m)
+	slices.SortFunc(values, func(a, b V) int { return compareIds(id(a), id(b)) })
+	return values
+}
+
+func mapErr[T, R any](s []T, f func(T) (R, error)) ([]R, error) {
+	result := make([]R, 0, len(s))
+	for _, v := range s {
+		r, err := f(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// mapNilErr maps the slice like mapErr, keeping it nil if it is nil.
+func mapNilErr[T, R any](s []T, f func(T) (R, error)) ([]R, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return mapErr(s, f)
+}
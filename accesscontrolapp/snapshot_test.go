@@ -0,0 +1,179 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/hashgraph"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldResumeFromSnapshotAtEveryOp(t *testing.T) {
+	LogMembershipChanges = false
+	opList := snapshotOps(rand.New(rand.NewSource(int64(0))))
+	app, err := ExecuteOpList(opList, 100, 2)
+	assert.NoError(t, err)
+	expected := encodeSnapshot(t, app)
+	for _, op := range opList {
+		partial, err := ExecuteOpListUntil(opList, op.idx, 100, 2)
+		assert.NoError(t, err)
+		snapshot := decodeSnapshot(t, encodeSnapshot(t, partial))
+		resumed, err := ExecuteOpListFrom(snapshot, opList, 100, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, "", app.Diff(resumed))
+		assert.Equal(t, string(expected), string(encodeSnapshot(t, resumed)))
+	}
+}
+
+func TestShouldExecuteFromInitWithStaleSnapshot(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	add1Node := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 30)), []*hashgraph.OpNode{firstNode})
+	add2Node := hashgraph.NewNode(crdt.Add(ids[0], ids[2], "", makePtRange(30, 60)), []*hashgraph.OpNode{add1Node})
+	postNode := hashgraph.NewNode(crdt.Post(ids[1], "first"), []*hashgraph.OpNode{add2Node})
+	hashgraph.NewNode(crdt.Post(ids[2], "second"), []*hashgraph.OpNode{postNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	snapshot, err := app.Snapshot()
+	assert.NoError(t, err)
+	hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{add2Node})
+	crdt.Clear()
+	hashgraph.RunHashgraph(0, firstNode)
+	expected, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	resumed, err := ExecuteCRDTFrom(snapshot, &crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "", expected.Diff(resumed))
+	assert.Equal(t, 1, len(resumed.Msgs))
+	resumed, err = ExecuteCRDTFrom(snapshot, &crdt, 200, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resumed.numPoints)
+}
+
+func TestShouldNotResumeWithinConcurrentRemovals(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	for i := 0; i < 20; i++ {
+		crdt := NewCRDT()
+		ids := genIds(2, r)
+		firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+		addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 50)), []*hashgraph.OpNode{firstNode})
+		deal := committeeDealNode(&crdt, ids, addNode, NewApp(100, 2))
+		hashgraph.NewNode(crdt.Rem(ids[1], ids[0]), []*hashgraph.OpNode{deal})
+		hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{deal})
+		hashgraph.RunHashgraph(0, firstNode)
+		opList := crdt.GetOperationList()
+		partial, err := ExecuteOpList(opList[:5], 100, 2)
+		assert.NoError(t, err)
+		snapshot, err := partial.Snapshot()
+		assert.NoError(t, err)
+		expected, err := ExecuteOpList(opList, 100, 2)
+		assert.NoError(t, err)
+		resumed, err := ExecuteOpListFrom(snapshot, opList, 100, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, "", expected.Diff(resumed))
+	}
+}
+
+func TestShouldResumeWithEncryptedValues(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	keys := genKeys(2, r)
+	crdt.SetKey(ids[0], keys[0])
+	crdt.SetKey(ids[1], keys[1])
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 50)), []*hashgraph.OpNode{firstNode})
+	deal := committeeDealNode(&crdt, ids, addNode, executedView(&crdt, firstNode))
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	snapshot := decodeSnapshot(t, encodeSnapshot(t, app))
+	rems := []*hashgraph.OpNode{
+		hashgraph.NewNode(crdt.Rem(ids[1], ids[0]), []*hashgraph.OpNode{deal}),
+		hashgraph.NewNode(crdt.Rem(ids[0], ids[1]), []*hashgraph.OpNode{deal}),
+	}
+	crdt.Clear()
+	hashgraph.RunHashgraph(0, firstNode)
+	pending, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	revealNodes(&crdt, pending, rems)
+	crdt.Clear()
+	hashgraph.RunHashgraph(0, firstNode)
+	expected, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	resumed, err := ExecuteCRDTFrom(snapshot, &crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "", expected.Diff(resumed))
+	assert.Equal(t, 1, len(resumed.users))
+	dealt, err := graphNodeSnapshot(app.graphNodes[deal.GetId()])
+	assert.NoError(t, err)
+	restored, err := graphNodeSnapshot(resumed.graphNodes[deal.GetId()])
+	assert.NoError(t, err)
+	assert.Equal(t, dealt.EncDeltaVals, restored.EncDeltaVals)
+	assert.NotNil(t, restored.EncDeltaVals[0])
+}
+
+func TestShouldRejectUnknownSnapshotVersion(t *testing.T) {
+	LogMembershipChanges = false
+	opList := snapshotOps(rand.New(rand.NewSource(int64(0))))
+	app, err := ExecuteOpList(opList[:2], 100, 2)
+	assert.NoError(t, err)
+	snapshot, err := app.Snapshot()
+	assert.NoError(t, err)
+	snapshot.Version++
+	_, err = ExecuteOpListFrom(snapshot, opList, 100, 2)
+	assert.Error(t, err)
+}
+
+// snapshotOps returns the operations of a group going through most kinds of operations, with a removal conflict.
+func snapshotOps(r *rand.Rand) []*Op {
+	crdt := NewCRDT()
+	nodes := snapshotNodes(&crdt, r)
+	hashgraph.RunHashgraph(0, nodes[0])
+	return crdt.GetOperationList()
+}
+
+// snapshotNodes adds the operations of snapshotOps to a hashgraph without running it. Returns the nodes in the order
+// they were created.
+func snapshotNodes(crdt *CRDT, r *rand.Rand) []*hashgraph.OpNode {
+	ids := genIds(4, r)
+	nodes := []*hashgraph.OpNode{hashgraph.NewNode(crdt.Init(ids[0], ""), nil)}
+	for i, id := range ids[1:] {
+		nodes = append(nodes, hashgraph.NewNode(crdt.Add(ids[0], id, "", makePtRange(i*20, (i+1)*20)), []*hashgraph.OpNode{nodes[i]}))
+	}
+	addNode := nodes[len(nodes)-1]
+	postNode := hashgraph.NewNode(crdt.Post(ids[1], "hello"), []*hashgraph.OpNode{addNode})
+	reactNode := hashgraph.NewNode(crdt.React(ids[2], postNode.GetId(), "👍"), []*hashgraph.OpNode{postNode})
+	editNode := hashgraph.NewNode(crdt.EditPost(ids[1], postNode.GetId(), "hello all"), []*hashgraph.OpNode{reactNode})
+	channelNode := hashgraph.NewNode(crdt.CreateChannel(ids[0], "general"), []*hashgraph.OpNode{editNode})
+	joinNode := hashgraph.NewNode(crdt.AddToChannel(ids[0], channelNode.GetId(), ids[3], makePtRange(0, 10)), []*hashgraph.OpNode{channelNode})
+	roleNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[2], RoleModerator), []*hashgraph.OpNode{joinNode})
+	proposeNode := hashgraph.NewNode(crdt.ProposeRemoval(ids[1], ids[2]), []*hashgraph.OpNode{roleNode})
+	transferNode := hashgraph.NewNode(crdt.Transfer(ids[0], ids[1], makePtRange(80, 85)), []*hashgraph.OpNode{proposeNode})
+	rem1Node := hashgraph.NewNode(crdt.Rem(ids[1], ids[3]), []*hashgraph.OpNode{transferNode})
+	rem2Node := hashgraph.NewNode(crdt.Rem(ids[3], ids[1]), []*hashgraph.OpNode{transferNode})
+	unbanNode := hashgraph.NewNode(crdt.Unban(ids[0], ids[1]), []*hashgraph.OpNode{rem2Node})
+	byeNode := hashgraph.NewNode(crdt.Post(ids[0], "bye"), []*hashgraph.OpNode{unbanNode})
+	return append(nodes, postNode, reactNode, editNode, channelNode, joinNode, roleNode, proposeNode, transferNode,
+		rem1Node, rem2Node, unbanNode, byeNode)
+}
+
+func encodeSnapshot(t *testing.T, app *App) []byte {
+	snapshot, err := app.Snapshot()
+	assert.NoError(t, err)
+	encoded, err := json.Marshal(snapshot)
+	assert.NoError(t, err)
+	return encoded
+}
+
+func decodeSnapshot(t *testing.T, encoded []byte) *Snapshot {
+	snapshot := &Snapshot{}
+	assert.NoError(t, json.Unmarshal(encoded, snapshot))
+	return snapshot
+}
@@ -0,0 +1,90 @@
+package accesscontrolapp
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/petar/GoLLRB/llrb"
+	"github.com/samber/lo"
+	"maps"
+	"slices"
+)
+
+// Members returns the users currently in the group, sorted by id.
+func (app *App) Members() []*User {
+	members := lo.Values(app.users)
+	slices.SortFunc(members, func(a, b *User) int { return compareIds(a.Id, b.Id) })
+	return members
+}
+
+func (app *App) GetUser(id uuid.UUID) *User {
+	return app.users[id]
+}
+
+func (u *User) PrettyName() string {
+	return u.prettyName
+}
+
+// PointList returns the points held by the user in ascending order.
+func (u *User) PointList() []uint {
+	return pointList(u.Points)
+}
+
+func pointList(pts *llrb.LLRB) []uint {
+	points := make([]uint, 0, pts.Len())
+	pts.AscendGreaterOrEqual(pts.Min(), func(val llrb.Item) bool {
+		points = append(points, uint(val.(*pt).pt))
+		return true
+	})
+	return points
+}
+
+// Diff describes the first difference found in the membership, points and roles held, bans, channels and messages of
+// two apps.
+// Returns an empty string if there is none.
+func (app *App) Diff(other *App) string {
+	members, otherMembers := app.Members(), other.Members()
+	if len(members) != len(otherMembers) {
+		return fmt.Sprintf("%d members against %d members", len(members), len(otherMembers))
+	}
+	for _, tuple := range lo.Zip2(members, otherMembers) {
+		user, otherUser := tuple.Unpack()
+		if user.Id != otherUser.Id {
+			return fmt.Sprintf("member %s against member %s", user.Id, otherUser.Id)
+		} else if !slices.Equal(user.PointList(), otherUser.PointList()) {
+			return fmt.Sprintf("member %s holds %d points against %d points", user.Id, user.Points.Len(), otherUser.Points.Len())
+		} else if user.role != otherUser.role {
+			return fmt.Sprintf("member %s is %s against %s", user.Id, user.role, otherUser.role)
+		}
+	}
+	if banned, otherBanned := app.Banned(), other.Banned(); !slices.Equal(banned, otherBanned) {
+		return fmt.Sprintf("%d banned users against %d banned users", len(banned), len(otherBanned))
+	}
+	channels, otherChannels := app.Channels(), other.Channels()
+	if len(channels) != len(otherChannels) {
+		return fmt.Sprintf("%d channels against %d channels", len(channels), len(otherChannels))
+	}
+	for _, tuple := range lo.Zip2(channels, otherChannels) {
+		channel, otherChannel := tuple.Unpack()
+		if channel.Id != otherChannel.Id {
+			return fmt.Sprintf("channel %s against channel %s", channel.Id, otherChannel.Id)
+		} else if !slices.Equal(channel.Members(), otherChannel.Members()) {
+			return fmt.Sprintf("channel %s has %d members against %d members", channel.Id, len(channel.members), len(otherChannel.members))
+		}
+	}
+	if len(app.Msgs) != len(other.Msgs) {
+		return fmt.Sprintf("%d messages against %d messages", len(app.Msgs), len(other.Msgs))
+	}
+	for i, tuple := range lo.Zip2(app.Msgs, other.Msgs) {
+		msg, otherMsg := tuple.Unpack()
+		if !msg.equal(otherMsg) {
+			return fmt.Sprintf("message %d is %q against %q", i, msg.Text(), otherMsg.Text())
+		} else if !maps.Equal(app.ReactionCounts(msg.Id), other.ReactionCounts(otherMsg.Id)) {
+			return fmt.Sprintf("message %d has reactions %v against %v", i, app.ReactionCounts(msg.Id), other.ReactionCounts(otherMsg.Id))
+		}
+	}
+	return ""
+}
+
+func compareIds(a, b uuid.UUID) int {
+	return slices.Compare(a[:], b[:])
+}
@@ -0,0 +1,66 @@
+package accesscontrolapp
+
+import (
+	"github.com/google/uuid"
+	"log/slog"
+)
+
+// Thread is a message together with the threads of the replies to it, in the order they were posted.
+type Thread struct {
+	Msg     *Msg
+	Replies []*Thread
+}
+
+func (app *App) reply(op *Op) error {
+	reply := op.content.(*ReplyOp)
+	poster := app.users[reply.poster]
+	app.graphNodes[op.id] = app.postBNode(op)
+	if !app.hasPrevious(op) {
+		return reject(ReasonMissingPrevious, "previous operation ids do not exist")
+	} else if len(op.prevIds) == 0 {
+		return reject(ReasonNoPrevious, "reply operation must have at least one previous operation")
+	} else if poster == nil {
+		return reject(ReasonNotAMember, "operation poster is not a user")
+	} else if valid, reason := app.hasValidSignature(op, poster.pubKey); !valid {
+		return reason
+	} else if !poster.canWrite() {
+		return reject(ReasonReadOnly, "read-only users cannot post")
+	}
+	parent := app.GetMsg(reply.parent)
+	if parent == nil {
+		return reject(ReasonUnknownTarget, "parent post does not exist")
+	} else if !app.canAccess(reply.poster, parent.Channel) {
+		return reject(ReasonNotInChannel, "poster is not a member of the channel")
+	}
+	msg := Msg{
+		Id:      op.id,
+		Issuer:  reply.poster,
+		Content: reply.msg,
+		Parent:  reply.parent,
+		Channel: parent.Channel,
+	}
+	app.msgIdx[op.id] = len(app.Msgs)
+	app.Msgs = append(app.Msgs, msg)
+	slog.Debug("Replied to message", "poster", reply.poster, "parent", reply.parent, "msg", reply.msg)
+	app.emit(MessagePosted{Msg: msg})
+	return nil
+}
+
+// Threads returns the messages that are not replies, each with the replies to it nested below.
+func (app *App) Threads() []*Thread {
+	threads := make(map[uuid.UUID]*Thread)
+	roots := make([]*Thread, 0)
+	for i := range app.Msgs {
+		msg := &app.Msgs[i]
+		thread := &Thread{Msg: msg, Replies: make([]*Thread, 0)}
+		if msg.Id != uuid.Nil {
+			threads[msg.Id] = thread
+		}
+		if parent := threads[msg.Parent]; msg.Parent != uuid.Nil && parent != nil {
+			parent.Replies = append(parent.Replies, thread)
+		} else {
+			roots = append(roots, thread)
+		}
+	}
+	return roots
+}
@@ -0,0 +1,46 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/hashgraph"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldThreadReplies(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 10)
+	postNode := hashgraph.NewNode(crdt.Post(ids[0], "question"), []*hashgraph.OpNode{addNode})
+	replyNode := hashgraph.NewNode(crdt.Reply(ids[1], postNode.GetId(), "answer"), []*hashgraph.OpNode{postNode})
+	hashgraph.NewNode(crdt.Reply(ids[0], replyNode.GetId(), "thanks"), []*hashgraph.OpNode{replyNode})
+	hashgraph.NewNode(crdt.Post(ids[1], "unrelated"), []*hashgraph.OpNode{postNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(app.Msgs))
+	threads := app.Threads()
+	assert.Equal(t, 2, len(threads))
+	assert.Equal(t, "question", threads[0].Msg.Text())
+	assert.Equal(t, 1, len(threads[0].Replies))
+	assert.Equal(t, "answer", threads[0].Replies[0].Msg.Text())
+	assert.Equal(t, postNode.GetId(), threads[0].Replies[0].Msg.Parent)
+	assert.Equal(t, "thanks", threads[0].Replies[0].Replies[0].Msg.Text())
+	assert.Equal(t, "unrelated", threads[1].Msg.Text())
+	assert.Empty(t, threads[1].Replies)
+}
+
+func TestShouldFailToReplyToMissingPost(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(1, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	hashgraph.NewNode(crdt.Reply(ids[0], firstNode.GetId(), "reply to nothing"), []*hashgraph.OpNode{firstNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, app.Msgs)
+}
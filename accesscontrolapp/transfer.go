@@ -0,0 +1,53 @@
+package accesscontrolapp
+
+import (
+	"log/slog"
+)
+
+func (app *App) transfer(op *Op) error {
+	transfer := op.content.(*TransferOp)
+	if canTransfer, reason := app.canTransfer(op); !canTransfer {
+		app.graphNodes[op.id] = app.dummyBNode(op)
+		return reason
+	}
+	bnode := app.dummyBNode(op)
+	issuer := app.users[transfer.issuer]
+	recipient := app.users[transfer.recipient]
+	for _, p := range transfer.points {
+		issuer.Points.Delete(&pt{pt: int(p)})
+		recipient.Points.InsertNoReplace(&pt{pt: int(p)})
+	}
+	app.graphNodes[op.id] = bnode
+	app.openRefresh(op.id)
+	slog.Debug("Transferred points", "issuer", transfer.issuer, "recipient", transfer.recipient, "points", len(transfer.points))
+	app.emit(PointsTransferred{Op: op.id, From: transfer.issuer, To: transfer.recipient, Points: transfer.points})
+	if LogMembershipChanges {
+		app.Msgs = append(app.Msgs, Msg{Issuer: transfer.issuer, Content: createControlMsgf(cyan, "%s transferred %d points to %s", issuer.prettyName, len(transfer.points), recipient.prettyName)})
+	}
+	return nil
+}
+
+func (app *App) canTransfer(op *Op) (bool, *Rejection) {
+	if !app.hasPrevious(op) {
+		return false, reject(ReasonMissingPrevious, "previous operation ids do not exist")
+	} else if len(op.prevIds) == 0 {
+		return false, reject(ReasonNoPrevious, "transfer operation must have at least one previous operation")
+	}
+	transfer := op.content.(*TransferOp)
+	if transfer.issuer == transfer.recipient {
+		return false, reject(ReasonSelfTarget, "user cannot transfer points to themselves")
+	} else if len(transfer.points) == 0 {
+		return false, reject(ReasonInvalidPoints, "at least a single point must be given")
+	}
+	issuer := app.users[transfer.issuer]
+	if issuer == nil {
+		return false, reject(ReasonNotAMember, "operation issuer is not a user")
+	} else if app.users[transfer.recipient] == nil {
+		return false, reject(ReasonUnknownUser, "recipient is not in the system")
+	} else if valid, reason := app.hasValidSignature(op, issuer.pubKey); !valid {
+		return false, reason
+	} else if canGive, reason := canGivePoints(issuer.Points, transfer.points); !canGive {
+		return false, reason
+	}
+	return true, nil
+}
@@ -0,0 +1,74 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/hashgraph"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestShouldTransferPoints(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	hashgraph.NewNode(crdt.Transfer(ids[0], ids[1], makePtRange(10, 30)), []*hashgraph.OpNode{addNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, makePtRange(0, 30), app.users[ids[1]].PointList())
+	assert.Equal(t, makePtRange(30, 100), app.users[ids[0]].PointList())
+}
+
+func TestShouldTransferPointsBack(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	hashgraph.NewNode(crdt.Transfer(ids[1], ids[0], makePtRange(0, 5)), []*hashgraph.OpNode{addNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, makePtRange(5, 10), app.users[ids[1]].PointList())
+	assert.Equal(t, 95, app.users[ids[0]].Points.Len())
+}
+
+func TestShouldFailToTransferInvalidPoints(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	hashgraph.NewNode(crdt.Transfer(ids[1], ids[0], makePtRange(0, 10)), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Transfer(ids[1], ids[0], makePtRange(5, 15)), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Transfer(ids[1], ids[0], []uint{}), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Transfer(ids[1], ids[1], makePtRange(0, 5)), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Transfer(ids[1], ids[2], makePtRange(0, 5)), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Transfer(ids[2], ids[1], makePtRange(50, 55)), []*hashgraph.OpNode{addNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, makePtRange(0, 10), app.users[ids[1]].PointList())
+	assert.Equal(t, makePtRange(10, 100), app.users[ids[0]].PointList())
+}
+
+func TestShouldLogTransfer(t *testing.T) {
+	LogMembershipChanges = true
+	defer func() { LogMembershipChanges = false }()
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], "A"), nil)
+	addNode := hashgraph.NewNode(crdt.Add(ids[0], ids[1], "B", makePtRange(0, 10)), []*hashgraph.OpNode{firstNode})
+	hashgraph.NewNode(crdt.Transfer(ids[0], ids[1], makePtRange(10, 12)), []*hashgraph.OpNode{addNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(app.Msgs))
+	assert.Contains(t, app.Msgs[2].Content, "A transferred 2 points to B")
+}
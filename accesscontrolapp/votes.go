@@ -0,0 +1,174 @@
+package accesscontrolapp
+
+import (
+	"cmp"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"log/slog"
+	"math/big"
+	"slices"
+)
+
+// Members remove each other by vote by proposing the removal of a member and voting on the proposal.
+// Votes are weighted by the points of the voters when they are tallied, after every vote. The removal passes once the
+// points of the voters in favour exceed RemovalQuorum times the points of the target's side, made of the target and
+// the voters against it. The points of the target are then split among the voters in favour, in proportion to their stake.
+
+// RemovalQuorum is the fraction of the points of the target's side that the voters in favour of a removal must exceed.
+var RemovalQuorum = big.NewRat(1, 1)
+
+type removalProposal struct {
+	proposer uuid.UUID
+	target   uuid.UUID
+	votes    map[uuid.UUID]bool
+	decided  bool
+}
+
+func (app *App) proposeRemoval(op *Op) error {
+	propose := op.content.(*ProposeRemovalOp)
+	if canPropose, reason := app.canProposeRemoval(op); !canPropose {
+		app.graphNodes[op.id] = app.dummyBNode(op)
+		return reason
+	}
+	proposal := &removalProposal{
+		proposer: propose.issuer,
+		target:   propose.target,
+		votes:    map[uuid.UUID]bool{propose.issuer: true},
+	}
+	app.proposals[op.id] = proposal
+	slog.Debug("Proposed removal", "issuer", propose.issuer, "target", propose.target)
+	return app.tally(op, proposal)
+}
+
+func (app *App) canProposeRemoval(op *Op) (bool, *Rejection) {
+	propose := op.content.(*ProposeRemovalOp)
+	if !app.hasPrevious(op) {
+		return false, reject(ReasonMissingPrevious, "previous operation ids do not exist")
+	} else if len(op.prevIds) == 0 {
+		return false, reject(ReasonNoPrevious, "removal proposal must have at least one previous operation")
+	} else if propose.issuer == propose.target {
+		return false, reject(ReasonSelfTarget, "user cannot propose their own removal")
+	}
+	issuer := app.users[propose.issuer]
+	if issuer == nil {
+		return false, reject(ReasonNotAMember, "operation issuer is not a user")
+	} else if app.users[propose.target] == nil {
+		return false, reject(ReasonUnknownUser, "target of the proposal is not in the system")
+	} else if valid, reason := app.hasValidSignature(op, issuer.pubKey); !valid {
+		return false, reason
+	} else if !issuer.canWrite() {
+		return false, reject(ReasonReadOnly, "read-only users cannot propose removals")
+	}
+	return true, nil
+}
+
+func (app *App) vote(op *Op) error {
+	vote := op.content.(*VoteOp)
+	if canVote, reason := app.canVote(op); !canVote {
+		app.graphNodes[op.id] = app.dummyBNode(op)
+		return reason
+	}
+	proposal := app.proposals[vote.proposal]
+	proposal.votes[vote.issuer] = vote.inFavour
+	slog.Debug("Voted on removal", "issuer", vote.issuer, "target", proposal.target, "inFavour", vote.inFavour)
+	return app.tally(op, proposal)
+}
+
+func (app *App) canVote(op *Op) (bool, *Rejection) {
+	vote := op.content.(*VoteOp)
+	if !app.hasPrevious(op) {
+		return false, reject(ReasonMissingPrevious, "previous operation ids do not exist")
+	} else if len(op.prevIds) == 0 {
+		return false, reject(ReasonNoPrevious, "vote must have at least one previous operation")
+	}
+	issuer := app.users[vote.issuer]
+	proposal := app.proposals[vote.proposal]
+	if issuer == nil {
+		return false, reject(ReasonNotAMember, "operation issuer is not a user")
+	} else if proposal == nil {
+		return false, reject(ReasonUnknownTarget, "proposal does not exist")
+	} else if proposal.decided {
+		return false, reject(ReasonDecided, "proposal has already been decided")
+	} else if app.users[proposal.target] == nil {
+		return false, reject(ReasonUnknownUser, "target of the proposal is no longer in the system")
+	} else if proposal.target == vote.issuer {
+		return false, reject(ReasonSelfTarget, "user cannot vote on their own removal")
+	} else if valid, reason := app.hasValidSignature(op, issuer.pubKey); !valid {
+		return false, reason
+	} else if !issuer.canWrite() {
+		return false, reject(ReasonReadOnly, "read-only users cannot vote")
+	}
+	return true, nil
+}
+
+// tally removes the target of the proposal if the voters in favour hold enough points.
+// The operation casting the deciding vote refreshes the values of the points transferred.
+func (app *App) tally(op *Op, proposal *removalProposal) error {
+	target := app.users[proposal.target]
+	voters := app.votersInFavour(proposal)
+	favourPoints := lo.SumBy(voters, func(u *User) int { return u.Points.Len() })
+	sidePoints := target.Points.Len() + lo.SumBy(app.votersAgainst(proposal), func(u *User) int { return u.Points.Len() })
+	required := new(big.Rat).Mul(RemovalQuorum, big.NewRat(int64(sidePoints), 1))
+	if big.NewRat(int64(favourPoints), 1).Cmp(required) <= 0 {
+		app.graphNodes[op.id] = app.dummyBNode(op)
+		return nil
+	}
+	stakes := lo.Map(voters, func(u *User, _ int) uint64 { return uint64(u.Points.Len()) })
+	split := splitPoints(target.PointList(), stakes)
+	bnode := app.dummyBNode(op)
+	for i, voter := range voters {
+		for _, p := range split[i] {
+			voter.Points.InsertNoReplace(&pt{pt: int(p)})
+		}
+	}
+	app.deleteUser(proposal.target)
+	app.ban(target, op.id)
+	proposal.decided = true
+	app.graphNodes[op.id] = bnode
+	app.openRefresh(op.id)
+	slog.Debug("Removed user by vote", "target", proposal.target, "voters", len(voters))
+	app.emit(MemberRemoved{Op: op.id, Kind: Vote, Issuer: proposal.proposer, Removed: proposal.target})
+	if LogMembershipChanges {
+		app.Msgs = append(app.Msgs, Msg{Issuer: proposal.proposer, Content: createControlMsgf(red, "%s was removed by vote of %d members", target.prettyName, len(voters))})
+	}
+	return nil
+}
+
+// votersInFavour returns the members voting in favour of the proposal, sorted by id.
+func (app *App) votersInFavour(proposal *removalProposal) []*User {
+	return app.votersWith(proposal, true)
+}
+
+// votersAgainst returns the members voting against the proposal, sorted by id.
+func (app *App) votersAgainst(proposal *removalProposal) []*User {
+	return app.votersWith(proposal, false)
+}
+
+func (app *App) votersWith(proposal *removalProposal, inFavour bool) []*User {
+	voters := lo.FilterMap(lo.Entries(proposal.votes), func(vote lo.Entry[uuid.UUID, bool], _ int) (*User, bool) {
+		user := app.users[vote.Key]
+		return user, user != nil && vote.Value == inFavour
+	})
+	slices.SortFunc(voters, func(a, b *User) int { return compareIds(a.Id, b.Id) })
+	return voters
+}
+
+// splitPoints splits the points in consecutive runs proportional to the stakes, using the largest remainder method.
+// Remaining points go to the largest remainders first, breaking ties in favour of the earlier stakes.
+func splitPoints(points []uint, stakes []uint64) [][]uint {
+	total := lo.Sum(stakes)
+	n := uint64(len(points))
+	counts := lo.Map(stakes, func(stake uint64, _ int) uint64 { return n * stake / total })
+	byRemainder := lo.Range(len(stakes))
+	slices.SortStableFunc(byRemainder, func(a, b int) int { return cmp.Compare(n*stakes[b]%total, n*stakes[a]%total) })
+	for _, i := range byRemainder[:n-lo.Sum(counts)] {
+		counts[i]++
+	}
+	split := make([][]uint, len(stakes))
+	start := uint64(0)
+	for i, count := range counts {
+		split[i] = points[start : start+count]
+		start += count
+	}
+	return split
+}
@@ -0,0 +1,133 @@
+package accesscontrolapp
+
+import (
+	"dare_randomized_access_control/hashgraph"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// votingGroup creates a group of 100 points where every user after the first is given the same number of points,
+// the first user keeping the rest. Returns the first and the last membership operations.
+func votingGroup(crdt *CRDT, ids []uuid.UUID, given int) (*hashgraph.OpNode, *hashgraph.OpNode) {
+	firstNode := hashgraph.NewNode(crdt.Init(ids[0], ""), nil)
+	last := firstNode
+	for i, id := range ids[1:] {
+		last = hashgraph.NewNode(crdt.Add(ids[0], id, "", makePtRange(i*given, (i+1)*given)), []*hashgraph.OpNode{last})
+	}
+	return firstNode, last
+}
+
+func TestShouldRemoveByVote(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 30)
+	proposeNode := hashgraph.NewNode(crdt.ProposeRemoval(ids[1], ids[0]), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.Vote(ids[2], proposeNode.GetId(), true), []*hashgraph.OpNode{proposeNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(app.users))
+	assert.Nil(t, app.users[ids[0]])
+	assert.Equal(t, 50, app.users[ids[1]].Points.Len())
+	assert.Equal(t, 50, app.users[ids[2]].Points.Len())
+}
+
+func TestShouldNotRemoveWithoutQuorum(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(4, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 20)
+	proposeNode := hashgraph.NewNode(crdt.ProposeRemoval(ids[1], ids[0]), []*hashgraph.OpNode{addNode})
+	voteNode := hashgraph.NewNode(crdt.Vote(ids[2], proposeNode.GetId(), true), []*hashgraph.OpNode{proposeNode})
+	hashgraph.NewNode(crdt.Vote(ids[3], proposeNode.GetId(), false), []*hashgraph.OpNode{voteNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(app.users))
+	assert.Equal(t, 40, app.users[ids[0]].Points.Len())
+}
+
+func TestShouldCountVotesAgainstWithTarget(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(4, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 25)
+	proposeNode := hashgraph.NewNode(crdt.ProposeRemoval(ids[1], ids[0]), []*hashgraph.OpNode{addNode})
+	againstNode := hashgraph.NewNode(crdt.Vote(ids[3], proposeNode.GetId(), false), []*hashgraph.OpNode{proposeNode})
+	hashgraph.NewNode(crdt.Vote(ids[2], proposeNode.GetId(), true), []*hashgraph.OpNode{againstNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(app.users))
+}
+
+func TestShouldRespectRemovalQuorum(t *testing.T) {
+	LogMembershipChanges = false
+	defer func(quorum *big.Rat) { RemovalQuorum = quorum }(RemovalQuorum)
+	RemovalQuorum = big.NewRat(1, 2)
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(2, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 40)
+	hashgraph.NewNode(crdt.ProposeRemoval(ids[1], ids[0]), []*hashgraph.OpNode{addNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(app.users))
+	assert.Equal(t, 100, app.users[ids[1]].Points.Len())
+}
+
+func TestShouldRejectVotesOnDecidedProposal(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(4, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 25)
+	proposeNode := hashgraph.NewNode(crdt.ProposeRemoval(ids[1], ids[0]), []*hashgraph.OpNode{addNode})
+	voteNode := hashgraph.NewNode(crdt.Vote(ids[2], proposeNode.GetId(), true), []*hashgraph.OpNode{proposeNode})
+	hashgraph.NewNode(crdt.Vote(ids[3], proposeNode.GetId(), true), []*hashgraph.OpNode{voteNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(app.users))
+	assert.Equal(t, 75, app.users[ids[1]].Points.Len()+app.users[ids[2]].Points.Len())
+	assert.Equal(t, 25, app.users[ids[3]].Points.Len())
+}
+
+func TestShouldSplitPointsByStake(t *testing.T) {
+	points := makePtRange(0, 10)
+	split := splitPoints(points, []uint64{1, 1, 1})
+	assert.Equal(t, [][]uint{makePtRange(0, 4), makePtRange(4, 7), makePtRange(7, 10)}, split)
+	split = splitPoints(points, []uint64{10, 30, 60})
+	assert.Equal(t, [][]uint{makePtRange(0, 1), makePtRange(1, 4), makePtRange(4, 10)}, split)
+	split = splitPoints(points, []uint64{1, 2})
+	assert.Equal(t, [][]uint{makePtRange(0, 3), makePtRange(3, 10)}, split)
+}
+
+func TestShouldPreventReadOnlyUsersFromVoting(t *testing.T) {
+	LogMembershipChanges = false
+	r := rand.New(rand.NewSource(int64(0)))
+	crdt := NewCRDT()
+	ids := genIds(3, r)
+	firstNode, addNode := votingGroup(&crdt, ids, 30)
+	grantNode := hashgraph.NewNode(crdt.GrantRole(ids[0], ids[2], RoleReadOnly), []*hashgraph.OpNode{addNode})
+	hashgraph.NewNode(crdt.ProposeRemoval(ids[2], ids[1]), []*hashgraph.OpNode{grantNode})
+	proposeNode := hashgraph.NewNode(crdt.ProposeRemoval(ids[1], ids[0]), []*hashgraph.OpNode{grantNode})
+	hashgraph.NewNode(crdt.Vote(ids[2], proposeNode.GetId(), true), []*hashgraph.OpNode{proposeNode})
+	hashgraph.RunHashgraph(0, firstNode)
+	app, err := ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(app.users))
+	assert.Equal(t, 40, app.users[ids[0]].Points.Len())
+	rejected := app.RejectedBy(ids[2])
+	assert.Equal(t, 2, len(rejected))
+	assert.Equal(t, ReasonReadOnly, rejected[0].Rejection.Code)
+	assert.Equal(t, ReasonReadOnly, rejected[1].Rejection.Code)
+}
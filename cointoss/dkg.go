@@ -0,0 +1,27 @@
+package cointoss
+
+import (
+	"fmt"
+	"github.com/cloudflare/circl/secretsharing"
+	"io"
+)
+
+// Joint-Feldman distributed key generation.
+// Every participant deals a random secret with Feldman verifiable secret sharing. The secret combining the
+// contributions whose shares verify is the sum of their secrets, so it stays unknown as long as one of the
+// participants keeps their secret to themselves.
+
+// Contribution is the random secret dealt by a participant of the distributed key generation.
+type Contribution struct {
+	Shares     []secretsharing.Share
+	Commitment secretsharing.SecretCommitment
+}
+
+// NewContribution deals a random secret drawn from the reader.
+func NewContribution(rnd io.Reader, threshold uint, nodes uint) (Contribution, error) {
+	shares, commitment, err := ShareRandomSecretVerifiableFrom(rnd, threshold, nodes)
+	if err != nil {
+		return Contribution{}, fmt.Errorf("unable to deal contribution: %v", err)
+	}
+	return Contribution{Shares: shares, Commitment: commitment}, nil
+}
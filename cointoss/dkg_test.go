@@ -0,0 +1,23 @@
+package cointoss
+
+import (
+	"github.com/cloudflare/circl/group"
+	"github.com/stretchr/testify/assert"
+	mrand "math/rand/v2"
+	"testing"
+)
+
+func TestShouldDealVerifiableContribution(t *testing.T) {
+	threshold, nodes := uint(2), uint(10)
+	contribution, err := NewContribution(mrand.NewChaCha8([32]byte{1}), threshold, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, int(nodes), len(contribution.Shares))
+	assert.True(t, VerifyShares(threshold, contribution.Shares, contribution.Commitment))
+	secret, err := RecoverSecret(threshold, contribution.Shares)
+	assert.NoError(t, err)
+	assert.True(t, contribution.Commitment[0].IsEqual(group.Ristretto255.NewElement().MulGen(secret)))
+	// Contributions drawn from other randomness deal other secrets
+	other, err := NewContribution(mrand.NewChaCha8([32]byte{2}), threshold, nodes)
+	assert.NoError(t, err)
+	assert.False(t, VerifyShares(threshold, other.Shares, contribution.Commitment))
+}
@@ -0,0 +1,88 @@
+package cointoss
+
+import (
+	"fmt"
+	"github.com/cloudflare/circl/group"
+	"github.com/cloudflare/circl/secretsharing"
+	"github.com/samber/lo"
+	"io"
+)
+
+// Shares are encrypted to their owners by masking their values with a scalar derived from a Diffie-Hellman exchange
+// between an ephemeral key of the dealer and the owner's key. Only the owner and the dealer can remove the mask.
+
+// EncryptedShare is a share whose value is hidden from everyone except its owner.
+type EncryptedShare struct {
+	ID        group.Scalar
+	Ephemeral group.Element
+	Masked    group.Scalar
+}
+
+// ShareKey is the key pair a user decrypts the shares dealt to them with.
+type ShareKey struct {
+	Private group.Scalar
+	Public  group.Element
+}
+
+// DeriveShareKey deterministically derives a share key from secret key material.
+func DeriveShareKey(seed []byte) ShareKey {
+	private := group.Ristretto255.HashToScalar(seed, []byte("share_key"))
+	return ShareKey{
+		Private: private,
+		Public:  group.Ristretto255.NewElement().MulGen(private),
+	}
+}
+
+// EncryptShares encrypts the shares to the owner of the public key, using a single ephemeral key drawn from the reader.
+func EncryptShares(shares []secretsharing.Share, pubKey group.Element, rnd io.Reader) ([]EncryptedShare, error) {
+	ephemeralKey, err := RandomScalarFrom(rnd)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral := group.Ristretto255.NewElement().MulGen(ephemeralKey)
+	shared := mulPoint(pubKey, ephemeralKey)
+	encrypted := make([]EncryptedShare, 0, len(shares))
+	for _, share := range shares {
+		mask, err := shareMask(shared, share.ID)
+		if err != nil {
+			return nil, err
+		}
+		encrypted = append(encrypted, EncryptedShare{
+			ID:        share.ID,
+			Ephemeral: ephemeral,
+			Masked:    AddScalar(share.Value, mask),
+		})
+	}
+	return encrypted, nil
+}
+
+// DecryptShares recovers the shares encrypted to the owner of the private key.
+func DecryptShares(encrypted []EncryptedShare, privKey group.Scalar) ([]secretsharing.Share, error) {
+	sharedKeys := make(map[group.Element]group.Element)
+	shares := make([]secretsharing.Share, 0, len(encrypted))
+	for _, enc := range encrypted {
+		shared := sharedKeys[enc.Ephemeral]
+		if shared == nil {
+			shared = mulPoint(enc.Ephemeral, privKey)
+			sharedKeys[enc.Ephemeral] = shared
+		}
+		mask, err := shareMask(shared, enc.ID)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, secretsharing.Share{ID: enc.ID, Value: sub(enc.Masked, mask)})
+	}
+	return shares, nil
+}
+
+func shareMask(shared group.Element, id group.Scalar) (group.Scalar, error) {
+	sharedBytes, err := shared.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate bytes from shared key: %v", err)
+	}
+	idBytes, err := id.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate bytes from share id: %v", err)
+	}
+	return group.Ristretto255.HashToScalar(lo.Flatten([][]byte{sharedBytes, idBytes}), []byte("share_mask")), nil
+}
@@ -0,0 +1,39 @@
+package cointoss
+
+import (
+	"crypto/rand"
+	"github.com/cloudflare/circl/secretsharing"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestShouldDecryptSharesWithOwnerKey(t *testing.T) {
+	key := DeriveShareKey([]byte("owner"))
+	shares, commitment, err := ShareRandomSecretVerifiableFrom(rand.Reader, 2, 10)
+	assert.NoError(t, err)
+	encrypted, err := EncryptShares(shares, key.Public, rand.Reader)
+	assert.NoError(t, err)
+	assert.True(t, lo.NoneBy(lo.Zip2(shares, encrypted), func(tuple lo.Tuple2[secretsharing.Share, EncryptedShare]) bool {
+		return tuple.A.Value.IsEqual(tuple.B.Masked)
+	}))
+	decrypted, err := DecryptShares(encrypted, key.Private)
+	assert.NoError(t, err)
+	assert.True(t, lo.EveryBy(lo.Zip2(shares, decrypted), func(tuple lo.Tuple2[secretsharing.Share, secretsharing.Share]) bool {
+		return tuple.A.ID.IsEqual(tuple.B.ID) && tuple.A.Value.IsEqual(tuple.B.Value)
+	}))
+	assert.True(t, VerifyShares(2, decrypted, commitment))
+}
+
+func TestShouldNotDecryptSharesWithOtherKey(t *testing.T) {
+	key := DeriveShareKey([]byte("owner"))
+	other := DeriveShareKey([]byte("other"))
+	shares, commitment, err := ShareRandomSecretVerifiableFrom(rand.Reader, 2, 10)
+	assert.NoError(t, err)
+	encrypted, err := EncryptShares(shares, key.Public, rand.Reader)
+	assert.NoError(t, err)
+	decrypted, err := DecryptShares(encrypted, other.Private)
+	assert.NoError(t, err)
+	assert.False(t, VerifyShare(2, decrypted[0], commitment))
+	assert.True(t, DeriveShareKey([]byte("owner")).Public.IsEqual(key.Public))
+}
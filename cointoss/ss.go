@@ -6,12 +6,17 @@ import (
 	"encoding/binary"
 	"fmt"
 	"github.com/cloudflare/circl/group"
+	"github.com/cloudflare/circl/math/polynomial"
 	"github.com/cloudflare/circl/secretsharing"
 	"github.com/samber/lo"
-	"math"
+	"io"
+	"math/big"
+	mrand "math/rand/v2"
 	"unsafe"
 )
 
+const float64Precision = 53
+
 // PointShare is a secret share hidden in a group operation.
 // This is used in the coin tossing scheme to hide the secret while making it usable as a randomness source.
 type PointShare struct {
@@ -23,6 +28,36 @@ func ShareRandomSecret(threshold uint, nodes uint) []secretsharing.Share {
 	return ShareSecret(threshold, nodes, group.Ristretto255.RandomScalar(rand.Reader))
 }
 
+func randomCoefficientsFrom(rnd io.Reader, threshold uint) ([]group.Scalar, error) {
+	coeffs := make([]group.Scalar, threshold+1)
+	for i := range coeffs {
+		coeff, err := RandomScalarFrom(rnd)
+		if err != nil {
+			return nil, err
+		}
+		coeffs[i] = coeff
+	}
+	return coeffs, nil
+}
+
+func evaluateShares(coeffs []group.Scalar, nodes uint) []secretsharing.Share {
+	poly := polynomial.New(coeffs)
+	return lo.Times(int(nodes), func(i int) secretsharing.Share {
+		id := NewScalar(uint64(i + 1))
+		return secretsharing.Share{ID: id, Value: poly.Evaluate(id)}
+	})
+}
+
+// RandomScalarFrom draws a scalar from the reader.
+// Unlike group.Ristretto255.RandomScalar, which ignores the reader it is given, the scalar depends only on the bytes read.
+func RandomScalarFrom(rnd io.Reader) (group.Scalar, error) {
+	buf := make([]byte, 2*sha256.Size)
+	if _, err := io.ReadFull(rnd, buf); err != nil {
+		return nil, fmt.Errorf("unable to read randomness for scalar: %v", err)
+	}
+	return group.Ristretto255.HashToScalar(buf, []byte("random_scalar")), nil
+}
+
 func ShareSecret(threshold uint, nodes uint, secret group.Scalar) []secretsharing.Share {
 	secretSharing := secretsharing.New(rand.Reader, threshold, secret)
 	return secretSharing.Share(nodes)
@@ -39,6 +74,16 @@ func ShareToPoint(share secretsharing.Share, base group.Element) PointShare {
 	}
 }
 
+// NewPointShare rebuilds the point share with the given id, such as one published by its owner.
+func NewPointShare(id group.Scalar, point group.Element) PointShare {
+	return PointShare{id: id, Point: point}
+}
+
+// ID returns the id of the share hidden in the point.
+func (s PointShare) ID() group.Scalar {
+	return s.id
+}
+
 func RecoverSecretFromPoints(shares []PointShare) group.Element {
 	indices := lo.Map(shares, func(share PointShare, _ int) group.Scalar { return share.id })
 	coefficients := lo.Map(indices, func(i group.Scalar, _ int) group.Scalar { return lagrangeCoefficient(i, indices) })
@@ -61,12 +106,87 @@ func lagrangeCoefficient(i group.Scalar, indices []group.Scalar) group.Scalar {
 	return mulScalar(numerators, inv(denominators))
 }
 
+// HashPointToDouble maps a group element to a value uniformly distributed in [0, 1).
+// The value has the 53 bits of precision of a float64 mantissa.
 func HashPointToDouble(point group.Element) (float64, error) {
+	hashed, err := hashPoint(point)
+	if err != nil {
+		return -1, err
+	}
+	val := binary.BigEndian.Uint64(hashed[:unsafe.Sizeof(uint64(0))])
+	return float64(val>>(64-float64Precision)) / float64(uint64(1)<<float64Precision), nil
+}
+
+// HashPointToRat maps a group element to a value uniformly distributed in [0, 1), represented exactly as a
+// fraction with denominator 2^256.
+func HashPointToRat(point group.Element) (*big.Rat, error) {
+	hashed, err := hashPoint(point)
+	if err != nil {
+		return nil, err
+	}
+	num := new(big.Int).SetBytes(hashed[:])
+	den := new(big.Int).Lsh(big.NewInt(1), uint(len(hashed)*8))
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+// IsCoinBelow compares, without rounding, the value the point maps to with the fraction num/den.
+// A coin is below num/den with probability num/den.
+func IsCoinBelow(point group.Element, num, den uint64) (bool, error) {
+	if den == 0 {
+		return false, fmt.Errorf("denominator must not be zero")
+	}
+	coin, err := HashPointToRat(point)
+	if err != nil {
+		return false, err
+	}
+	threshold := new(big.Rat).SetFrac(new(big.Int).SetUint64(num), new(big.Int).SetUint64(den))
+	return coin.Cmp(threshold) < 0, nil
+}
+
+// PointWeightedOrder orders participants by drawing them one at a time, without replacement, with probability
+// proportional to their weight among those not yet drawn. The first participant is the one whose range of the total
+// weight holds the value the point maps to, compared exactly with IsCoinBelow, so it is drawn with probability equal to
+// its share of the total weight. The following draws are seeded by the hash of the point.
+func PointWeightedOrder(point group.Element, weights []uint64) ([]int, error) {
+	if lo.SomeBy(weights, func(w uint64) bool { return w == 0 }) {
+		return nil, fmt.Errorf("weights must be positive")
+	}
+	total := lo.Sum(weights)
+	first, cumulative := 0, weights[0]
+	for ; first < len(weights)-1; first++ {
+		isBelow, err := IsCoinBelow(point, cumulative, total)
+		if err != nil {
+			return nil, err
+		} else if isBelow {
+			break
+		}
+		cumulative += weights[first+1]
+	}
+	hashed, err := hashPoint(point)
+	if err != nil {
+		return nil, err
+	}
+	r := mrand.New(mrand.NewChaCha8(hashed))
+	order := []int{first}
+	remaining := lo.Without(lo.Range(len(weights)), first)
+	for len(remaining) > 0 {
+		total := lo.SumBy(remaining, func(i int) uint64 { return weights[i] })
+		draw := r.Uint64N(total)
+		pos := 0
+		for draw >= weights[remaining[pos]] {
+			draw -= weights[remaining[pos]]
+			pos++
+		}
+		order = append(order, remaining[pos])
+		remaining = append(remaining[:pos], remaining[pos+1:]...)
+	}
+	return order, nil
+}
+
+func hashPoint(point group.Element) ([sha256.Size]byte, error) {
 	pointMarshal, err := point.MarshalBinary()
 	if err != nil {
-		return -1, fmt.Errorf("unable to generate bytes from Point: %v", err)
+		return [sha256.Size]byte{}, fmt.Errorf("unable to generate bytes from Point: %v", err)
 	}
-	hashed := sha256.Sum256(pointMarshal)
-	val := binary.LittleEndian.Uint64(hashed[:unsafe.Sizeof(uint64(0))])
-	return float64(val / math.MaxUint64), err
+	return sha256.Sum256(pointMarshal), nil
 }
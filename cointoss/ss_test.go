@@ -10,6 +10,7 @@ import (
 	"github.com/cloudflare/circl/zk/dleq"
 	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
+	"math/big"
 	"testing"
 )
 
@@ -107,3 +108,100 @@ func TestHashPointToDouble(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, val >= 0 && val <= 1)
 }
+
+func TestHashPointToDoubleShouldBeUniform(t *testing.T) {
+	g := group.Ristretto255
+	numBuckets := 10
+	numSamples := 10000
+	buckets := make([]int, numBuckets)
+	sum := 0.0
+	for i := 0; i < numSamples; i++ {
+		point := g.HashToElement([]byte(fmt.Sprintf("point %d", i)), []byte("ss_tests"))
+		val, err := HashPointToDouble(point)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, val, 0.0)
+		assert.Less(t, val, 1.0)
+		buckets[int(val*float64(numBuckets))]++
+		sum += val
+	}
+	assert.InDelta(t, 0.5, sum/float64(numSamples), 0.01)
+	expected := float64(numSamples) / float64(numBuckets)
+	chiSquare := lo.SumBy(buckets, func(observed int) float64 {
+		return (float64(observed) - expected) * (float64(observed) - expected) / expected
+	})
+	// Critical value of the chi-square distribution with 9 degrees of freedom at a significance of 0.001
+	assert.Less(t, chiSquare, 27.877)
+}
+
+func TestHashPointToRatShouldMatchDouble(t *testing.T) {
+	g := group.Ristretto255
+	for i := 0; i < 100; i++ {
+		point := g.HashToElement([]byte(fmt.Sprintf("point %d", i)), []byte("ss_tests"))
+		rat, err := HashPointToRat(point)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, rat.Sign(), 0)
+		assert.Less(t, rat.Cmp(big.NewRat(1, 1)), 0)
+		val, err := HashPointToDouble(point)
+		assert.NoError(t, err)
+		ratVal, _ := rat.Float64()
+		assert.InDelta(t, ratVal, val, 1e-15)
+	}
+}
+
+func TestIsCoinBelowShouldHandleBounds(t *testing.T) {
+	g := group.Ristretto255
+	below, above := 0, 0
+	for i := 0; i < 1000; i++ {
+		point := g.HashToElement([]byte(fmt.Sprintf("point %d", i)), []byte("ss_tests"))
+		isBelow, err := IsCoinBelow(point, 0, 10)
+		assert.NoError(t, err)
+		assert.False(t, isBelow)
+		isBelow, err = IsCoinBelow(point, 10, 10)
+		assert.NoError(t, err)
+		assert.True(t, isBelow)
+		isBelow, err = IsCoinBelow(point, 1, 2)
+		assert.NoError(t, err)
+		if isBelow {
+			below++
+		} else {
+			above++
+		}
+	}
+	assert.InDelta(t, 500, below, 60)
+	assert.InDelta(t, 500, above, 60)
+	_, err := IsCoinBelow(g.Generator(), 1, 0)
+	assert.Error(t, err)
+}
+
+func TestPointWeightedOrderShouldFollowWeights(t *testing.T) {
+	g := group.Ristretto255
+	weights := []uint64{10, 30, 60}
+	firsts := make([]int, len(weights))
+	for i := 0; i < 2000; i++ {
+		point := g.HashToElement([]byte(fmt.Sprintf("point %d", i)), []byte("ss_tests"))
+		order, err := PointWeightedOrder(point, weights)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []int{0, 1, 2}, order)
+		again, err := PointWeightedOrder(point, weights)
+		assert.NoError(t, err)
+		assert.Equal(t, order, again)
+		firsts[order[0]]++
+	}
+	assert.InDelta(t, 200, firsts[0], 60)
+	assert.InDelta(t, 600, firsts[1], 90)
+	assert.InDelta(t, 1200, firsts[2], 90)
+	_, err := PointWeightedOrder(g.Generator(), []uint64{1, 0})
+	assert.Error(t, err)
+}
+
+func TestPointWeightedOrderShouldDrawFirstWithExactCoin(t *testing.T) {
+	g := group.Ristretto255
+	for i := 0; i < 200; i++ {
+		point := g.HashToElement([]byte(fmt.Sprintf("point %d", i)), []byte("ss_tests"))
+		order, err := PointWeightedOrder(point, []uint64{3, 5})
+		assert.NoError(t, err)
+		isBelow, err := IsCoinBelow(point, 3, 8)
+		assert.NoError(t, err)
+		assert.Equal(t, isBelow, order[0] == 0)
+	}
+}
@@ -0,0 +1,54 @@
+package cointoss
+
+import (
+	"crypto"
+	"crypto/rand"
+	"fmt"
+	"github.com/cloudflare/circl/group"
+	"github.com/cloudflare/circl/secretsharing"
+	"github.com/cloudflare/circl/zk/dleq"
+)
+
+var dleqParams = dleq.Params{G: group.Ristretto255, H: crypto.SHA256, DST: []byte("coin_toss_share")}
+
+// VerifiablePointShare is a point share published by its owner together with a proof that it was computed with the
+// same scalar as the public commitment to the owner's share.
+type VerifiablePointShare struct {
+	PointShare
+	Proof *dleq.Proof
+}
+
+// ShareCommitment is the public commitment to the share with the given id, hidden in the generator of the group.
+type ShareCommitment struct {
+	ID    group.Scalar
+	Value group.Element
+}
+
+func CommitShare(share secretsharing.Share) ShareCommitment {
+	return ShareCommitment{
+		ID:    share.ID,
+		Value: mulPoint(group.Ristretto255.Generator(), share.Value),
+	}
+}
+
+// NewVerifiablePointShare hides the share in the base and proves that the discrete log of the result in base
+// equals the discrete log of the share commitment in the generator.
+func NewVerifiablePointShare(share secretsharing.Share, base group.Element) (VerifiablePointShare, error) {
+	pointShare := ShareToPoint(share, base)
+	gen := group.Ristretto255.Generator()
+	prover := dleq.Prover{Params: dleqParams}
+	proof, err := prover.Prove(share.Value, gen, CommitShare(share).Value, base, pointShare.Point, rand.Reader)
+	if err != nil {
+		return VerifiablePointShare{}, fmt.Errorf("unable to prove point share: %v", err)
+	}
+	return VerifiablePointShare{PointShare: pointShare, Proof: proof}, nil
+}
+
+// Verify checks the share against the public commitment to the share with the same id.
+func (s VerifiablePointShare) Verify(commitment ShareCommitment, base group.Element) bool {
+	if s.Proof == nil || s.Point == nil || s.id == nil || commitment.Value == nil || !s.id.IsEqual(commitment.ID) {
+		return false
+	}
+	verifier := dleq.Verifier{Params: dleqParams}
+	return verifier.Verify(group.Ristretto255.Generator(), commitment.Value, base, s.Point, s.Proof)
+}
@@ -0,0 +1,92 @@
+package cointoss
+
+import (
+	"crypto/rand"
+	"github.com/cloudflare/circl/group"
+	"github.com/cloudflare/circl/secretsharing"
+	"github.com/cloudflare/circl/zk/dleq"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestShouldVerifyHonestPointShares(t *testing.T) {
+	g := group.Ristretto255
+	threshold := uint(3)
+	base := g.HashToElement([]byte("base"), []byte("verifiable_tests"))
+	shares := ShareSecret(threshold, 10, g.NewScalar().SetUint64(42))
+	pointShares, commitments := publishShares(t, shares, base)
+	assert.True(t, lo.EveryBy(lo.Range(len(shares)), func(i int) bool { return pointShares[i].Verify(commitments[i], base) }))
+	recovered := RecoverSecretFromPoints(verifiedPoints(pointShares, commitments, base))
+	assert.True(t, recovered.IsEqual(g.NewElement().Mul(base, g.NewScalar().SetUint64(42))))
+}
+
+func TestShouldIgnoreTamperedPointShares(t *testing.T) {
+	g := group.Ristretto255
+	threshold := uint(3)
+	base := g.HashToElement([]byte("base"), []byte("verifiable_tests"))
+	shares := ShareSecret(threshold, 6, g.NewScalar().SetUint64(42))
+	pointShares, commitments := publishShares(t, shares, base)
+	// The first owner publishes a different point, with a proof made for its own scalar
+	forged := g.RandomScalar(rand.Reader)
+	forgedShare, err := NewVerifiablePointShare(secretsharing.Share{ID: shares[0].ID, Value: forged}, base)
+	assert.NoError(t, err)
+	pointShares[0] = forgedShare
+	assert.False(t, pointShares[0].Verify(commitments[0], base))
+	// The second owner claims the id of the third
+	pointShares[1].id = shares[2].ID
+	assert.False(t, pointShares[1].Verify(commitments[1], base))
+	verified := verifiedPoints(pointShares, commitments, base)
+	assert.Equal(t, int(threshold+1), len(verified))
+	recovered := RecoverSecretFromPoints(verified)
+	assert.True(t, recovered.IsEqual(g.NewElement().Mul(base, g.NewScalar().SetUint64(42))))
+}
+
+func TestShouldRejectPointSharesInOtherBase(t *testing.T) {
+	g := group.Ristretto255
+	threshold := uint(3)
+	base := g.HashToElement([]byte("base"), []byte("verifiable_tests"))
+	otherBase := g.HashToElement([]byte("other base"), []byte("verifiable_tests"))
+	shares := ShareSecret(threshold, 5, g.NewScalar().SetUint64(42))
+	pointShares, commitments := publishShares(t, shares, base)
+	// Two owners publish their shares hidden in the wrong base
+	for i := 0; i < 2; i++ {
+		wrongBase, err := NewVerifiablePointShare(shares[i], otherBase)
+		assert.NoError(t, err)
+		pointShares[i] = wrongBase
+	}
+	assert.Equal(t, 3, len(verifiedPoints(pointShares, commitments, base)))
+}
+
+func TestShouldVerifyRebuiltPointShares(t *testing.T) {
+	g := group.Ristretto255
+	threshold := uint(3)
+	base := g.HashToElement([]byte("base"), []byte("verifiable_tests"))
+	shares := ShareSecret(threshold, 4, g.NewScalar().SetUint64(42))
+	pointShares, commitments := publishShares(t, shares, base)
+	rebuilt := lo.Map(pointShares, func(share VerifiablePointShare, _ int) VerifiablePointShare {
+		proofBytes, err := share.Proof.MarshalBinary()
+		assert.NoError(t, err)
+		proof := &dleq.Proof{}
+		assert.NoError(t, proof.UnmarshalBinary(g, proofBytes))
+		return VerifiablePointShare{PointShare: NewPointShare(share.ID(), share.Point), Proof: proof}
+	})
+	assert.True(t, lo.EveryBy(lo.Range(len(shares)), func(i int) bool { return rebuilt[i].Verify(commitments[i], base) }))
+	assert.True(t, rebuilt[1].ID().IsEqual(shares[1].ID))
+}
+
+// verifiedPoints returns the point shares whose proofs verify against the commitment with the same index.
+func verifiedPoints(shares []VerifiablePointShare, commitments []ShareCommitment, base group.Element) []PointShare {
+	return lo.FilterMap(shares, func(share VerifiablePointShare, i int) (PointShare, bool) {
+		return share.PointShare, share.Verify(commitments[i], base)
+	})
+}
+
+func publishShares(t *testing.T, shares []secretsharing.Share, base group.Element) ([]VerifiablePointShare, []ShareCommitment) {
+	pointShares := lo.Map(shares, func(share secretsharing.Share, _ int) VerifiablePointShare {
+		pointShare, err := NewVerifiablePointShare(share, base)
+		assert.NoError(t, err)
+		return pointShare
+	})
+	return pointShares, lo.Map(shares, func(share secretsharing.Share, _ int) ShareCommitment { return CommitShare(share) })
+}
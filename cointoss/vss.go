@@ -0,0 +1,79 @@
+package cointoss
+
+import (
+	"crypto/rand"
+	"github.com/cloudflare/circl/group"
+	"github.com/cloudflare/circl/secretsharing"
+	"github.com/samber/lo"
+	"io"
+)
+
+// Feldman verifiable secret sharing.
+// Alongside the shares, the dealer publishes a commitment to each coefficient of the polynomial, hidden in the
+// generator of the group. Holders check their shares against the commitment without learning the secret.
+
+// ShareSecretVerifiable shares the secret like ShareSecret, and commits to the polynomial the shares come from.
+func ShareSecretVerifiable(threshold uint, nodes uint, secret group.Scalar) ([]secretsharing.Share, secretsharing.SecretCommitment) {
+	secretSharing := secretsharing.New(rand.Reader, threshold, secret)
+	return secretSharing.Share(nodes), secretSharing.CommitSecret()
+}
+
+// ShareRandomSecretVerifiableFrom shares a random secret drawn from the reader, and commits to the polynomial.
+// The same reader contents always yield the same shares and commitment.
+func ShareRandomSecretVerifiableFrom(rnd io.Reader, threshold uint, nodes uint) ([]secretsharing.Share, secretsharing.SecretCommitment, error) {
+	coeffs, err := randomCoefficientsFrom(rnd, threshold)
+	if err != nil {
+		return nil, nil, err
+	}
+	commitment := lo.Map(coeffs, func(coeff group.Scalar, _ int) group.Element {
+		return group.Ristretto255.NewElement().MulGen(coeff)
+	})
+	return evaluateShares(coeffs, nodes), commitment, nil
+}
+
+// VerifyShare checks that the share was dealt from the polynomial the commitment was made to.
+func VerifyShare(threshold uint, share secretsharing.Share, commitment secretsharing.SecretCommitment) bool {
+	return secretsharing.Verify(threshold, share, commitment)
+}
+
+// VerifyShares checks every share against the commitment at once.
+// It tests a linear combination of the shares weighted by the powers of a random scalar, so its cost barely depends
+// on the number of shares. A share that does not match the commitment goes unnoticed with negligible probability.
+func VerifyShares(threshold uint, shares []secretsharing.Share, commitment secretsharing.SecretCommitment) bool {
+	if len(commitment) != int(threshold+1) {
+		return false
+	} else if lo.SomeBy(shares, func(share secretsharing.Share) bool { return share.ID.IsZero() }) {
+		return false
+	}
+	g := group.Ristretto255
+	challenge, err := RandomScalarFrom(rand.Reader)
+	if err != nil {
+		return false
+	}
+	valueSum := g.NewScalar()
+	powerSums := lo.Times(len(commitment), func(_ int) group.Scalar { return g.NewScalar() })
+	weight := NewScalar(1)
+	for _, share := range shares {
+		weight = mulScalar(weight, challenge)
+		valueSum = AddScalar(valueSum, mulScalar(weight, share.Value))
+		power := weight
+		for j := range powerSums {
+			powerSums[j] = AddScalar(powerSums[j], power)
+			power = mulScalar(power, share.ID)
+		}
+	}
+	expected := lo.Reduce(lo.Zip2(commitment, powerSums), func(acc group.Element, tuple lo.Tuple2[group.Element, group.Scalar], _ int) group.Element {
+		return addPoint(acc, mulPoint(tuple.A, tuple.B))
+	}, g.Identity())
+	return g.NewElement().MulGen(valueSum).IsEqual(expected)
+}
+
+// CommitmentToShare derives, from the commitment to a polynomial, the commitment to the share with the given id.
+func CommitmentToShare(id group.Scalar, commitment secretsharing.SecretCommitment) ShareCommitment {
+	g := group.Ristretto255
+	value := g.Identity()
+	for i := len(commitment) - 1; i >= 0; i-- {
+		value = addPoint(mulPoint(value, id), commitment[i])
+	}
+	return ShareCommitment{ID: id, Value: value}
+}
@@ -0,0 +1,54 @@
+package cointoss
+
+import (
+	"crypto/rand"
+	"github.com/cloudflare/circl/group"
+	"github.com/cloudflare/circl/secretsharing"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	mrand "math/rand/v2"
+	"testing"
+)
+
+func TestShouldVerifyDealtShares(t *testing.T) {
+	threshold := uint(5)
+	shares, commitment := ShareSecretVerifiable(threshold, 20, NewScalar(1234567890))
+	assert.True(t, lo.EveryBy(shares, func(share secretsharing.Share) bool { return VerifyShare(threshold, share, commitment) }))
+	assert.True(t, VerifyShares(threshold, shares, commitment))
+	assert.True(t, commitment[0].IsEqual(group.Ristretto255.NewElement().MulGen(NewScalar(1234567890))))
+}
+
+func TestShouldDetectInconsistentShares(t *testing.T) {
+	threshold := uint(5)
+	shares, commitment, err := ShareRandomSecretVerifiableFrom(rand.Reader, threshold, 20)
+	assert.NoError(t, err)
+	shares[7].Value = AddScalar(shares[7].Value, NewScalar(1))
+	assert.False(t, VerifyShare(threshold, shares[7], commitment))
+	assert.True(t, VerifyShare(threshold, shares[8], commitment))
+	assert.False(t, VerifyShares(threshold, shares, commitment))
+	assert.False(t, VerifyShares(threshold-1, shares, commitment))
+}
+
+func TestShouldDealVerifiableSharesDeterministically(t *testing.T) {
+	seed := [32]byte{2}
+	shares1, commitment1, err := ShareRandomSecretVerifiableFrom(mrand.NewChaCha8(seed), 2, 10)
+	assert.NoError(t, err)
+	shares2, commitment2, err := ShareRandomSecretVerifiableFrom(mrand.NewChaCha8(seed), 2, 10)
+	assert.NoError(t, err)
+	assert.True(t, lo.EveryBy(lo.Zip2(commitment1, commitment2), func(tuple lo.Tuple2[group.Element, group.Element]) bool {
+		return tuple.A.IsEqual(tuple.B)
+	}))
+	assert.True(t, lo.EveryBy(lo.Zip2(shares1, shares2), func(tuple lo.Tuple2[secretsharing.Share, secretsharing.Share]) bool {
+		return tuple.A.Value.IsEqual(tuple.B.Value)
+	}))
+	assert.True(t, VerifyShares(2, shares1, commitment1))
+}
+
+func TestShouldDeriveShareCommitments(t *testing.T) {
+	threshold := uint(3)
+	shares, commitment, err := ShareRandomSecretVerifiableFrom(rand.Reader, threshold, 10)
+	assert.NoError(t, err)
+	shareCommitment := CommitmentToShare(shares[4].ID, commitment)
+	assert.True(t, shareCommitment.Value.IsEqual(CommitShare(shares[4]).Value))
+	assert.False(t, shareCommitment.Value.IsEqual(CommitShare(shares[5]).Value))
+}
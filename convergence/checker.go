@@ -0,0 +1,135 @@
+// Package convergence checks that the access control app reaches the same state regardless of the order in which
+// the hashgraph delivers concurrent operations.
+// The operations of the scenario are added to a hashgraph one at a time, each followed by the deals and coin shares the
+// users owe once it is delivered, as the demo issues them. The hashgraph is built once and then delivered in the
+// order decided by each seed, so that every seed sees the same deals.
+package convergence
+
+import (
+	"dare_randomized_access_control/accesscontrolapp"
+	"dare_randomized_access_control/hashgraph"
+	"dare_randomized_access_control/scenario"
+	"fmt"
+	"github.com/samber/lo"
+)
+
+type Checker struct {
+	NumPoints int
+	Threshold int
+	// Schedule delivers the operations reachable from the node to the CRDT in an order decided by the seed.
+	Schedule func(seed int, n hashgraph.Node)
+}
+
+// Report describes the outcome of a check. If the runs disagreed, Prefix holds the names of the operations in the
+// smallest prefix of the scenario where two seeds, SeedA and SeedB, lead to different states.
+type Report struct {
+	Converged bool
+	Prefix    []string
+	SeedA     int
+	SeedB     int
+	Diff      string
+}
+
+func NewChecker(numPoints, threshold int) *Checker {
+	return &Checker{
+		NumPoints: numPoints,
+		Threshold: threshold,
+		Schedule:  hashgraph.RunHashgraphCausal,
+	}
+}
+
+func (c *Checker) Check(sc *scenario.Scenario, seeds []int) (*Report, error) {
+	if len(seeds) < 2 {
+		return nil, fmt.Errorf("at least two seeds are needed to compare executions")
+	}
+	full, err := c.checkPrefix(sc, seeds)
+	if err != nil || full.Converged {
+		return full, err
+	}
+	for n := 1; n < len(sc.Ops); n++ {
+		report, err := c.checkPrefix(sc.Prefix(n), seeds)
+		if err != nil || !report.Converged {
+			return report, err
+		}
+	}
+	return full, nil
+}
+
+func (c *Checker) checkPrefix(sc *scenario.Scenario, seeds []int) (*Report, error) {
+	crdt := accesscontrolapp.NewCRDT()
+	root, err := c.build(sc, &crdt, seeds[0])
+	if err != nil {
+		return nil, err
+	}
+	reference, err := c.execute(&crdt, root, seeds[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, seed := range seeds[1:] {
+		app, err := c.execute(&crdt, root, seed)
+		if err != nil {
+			return nil, err
+		}
+		if diff := reference.Diff(app); diff != "" {
+			return &Report{
+				Converged: false,
+				Prefix:    opNames(sc),
+				SeedA:     seeds[0],
+				SeedB:     seed,
+				Diff:      diff,
+			}, nil
+		}
+	}
+	return &Report{Converged: true}, nil
+}
+
+// build adds the operations of the scenario to a hashgraph, issuing after each one the deals the committee members owe
+// to the refreshes and the coin shares the owners owe to the pending coin tosses, in the order decided by the seed.
+// Returns the initial node of the hashgraph.
+func (c *Checker) build(sc *scenario.Scenario, crdt *accesscontrolapp.CRDT, seed int) (*hashgraph.OpNode, error) {
+	rp := sc.NewReplayer(crdt)
+	rp.ContentIds = true
+	// dealt and revealed hold the deals and coin shares issued, so that each is issued once even if rejected
+	dealt := make(map[accesscontrolapp.PendingDeal]bool)
+	revealed := make(map[accesscontrolapp.PendingCoinShare]bool)
+	for rp.Step() != nil {
+		for {
+			app, err := c.execute(crdt, rp.Root(), seed)
+			if err != nil {
+				return nil, err
+			}
+			pending := lo.Filter(app.PendingDeals(), func(deal accesscontrolapp.PendingDeal, _ int) bool { return !dealt[deal] })
+			pendingShares := lo.Filter(app.PendingCoinShares(), func(share accesscontrolapp.PendingCoinShare, _ int) bool { return !revealed[share] })
+			if len(pending) == 0 && len(pendingShares) == 0 {
+				break
+			}
+			for _, deal := range pending {
+				dealt[deal] = true
+				rp.Issue(crdt.Deal(deal.Dealer, deal.Refresh, app))
+			}
+			for _, share := range pendingShares {
+				revealed[share] = true
+				rp.Issue(crdt.RevealCoinShares(share.Owner, share.Seed, app))
+			}
+		}
+	}
+	return rp.Root(), nil
+}
+
+// execute delivers the operations reachable from the root to the CRDT in the order decided by the seed, and clears
+// them once executed.
+func (c *Checker) execute(crdt *accesscontrolapp.CRDT, root *hashgraph.OpNode, seed int) (*accesscontrolapp.App, error) {
+	if root != nil {
+		c.Schedule(seed, root)
+	}
+	app, err := accesscontrolapp.ExecuteCRDT(crdt, c.NumPoints, c.Threshold)
+	crdt.Clear()
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute CRDT with seed %d: %v", seed, err)
+	}
+	return app, nil
+}
+
+func opNames(sc *scenario.Scenario) []string {
+	return lo.Map(sc.Ops, func(op *scenario.Op, _ int) string { return op.Name })
+}
@@ -0,0 +1,84 @@
+package convergence
+
+import (
+	"dare_randomized_access_control/accesscontrolapp"
+	"dare_randomized_access_control/hashgraph"
+	"dare_randomized_access_control/scenario"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const concurrentScenario = `
+seed 0
+user alice Alice
+user bob Bob
+user claire Claire
+init start alice
+add addBob alice bob 0..30 after start
+add addClaire alice claire 30..60 after start
+post bobPost bob "Bob: hi" after addBob
+post clairePost claire "Claire: hi" after addClaire
+post alicePost alice "Alice: hi both" after bobPost clairePost
+`
+
+func TestShouldConvergeOnDemo(t *testing.T) {
+	accesscontrolapp.LogMembershipChanges = false
+	sc, err := scenario.Load("../scenarios/demo.scenario")
+	assert.NoError(t, err)
+	report, err := NewChecker(600, 2).Check(sc, lo.Range(3))
+	assert.NoError(t, err)
+	assert.True(t, report.Converged)
+}
+
+func TestShouldReportSmallestDivergingPrefix(t *testing.T) {
+	accesscontrolapp.LogMembershipChanges = false
+	sc, err := scenario.Parse(strings.NewReader(concurrentScenario))
+	assert.NoError(t, err)
+	checker := NewChecker(100, 2)
+	// Odd seeds lose the post of Bob, whose id is derived from its content
+	crdt := accesscontrolapp.NewCRDT()
+	rp := sc.NewReplayer(&crdt)
+	rp.ContentIds = true
+	for rp.Step() != nil {
+	}
+	lost := rp.Node("bobPost").GetId()
+	checker.Schedule = func(seed int, n hashgraph.Node) {
+		hashgraph.RunHashgraphCausal(seed, &lossyNode{Node: n, lost: lo.Ternary(seed%2 == 1, lost, uuid.Nil)})
+	}
+	report, err := checker.Check(sc, []int{0, 2, 1, 3})
+	assert.NoError(t, err)
+	assert.False(t, report.Converged)
+	assert.Equal(t, []string{"start", "addBob", "addClaire", "bobPost"}, report.Prefix)
+	assert.Equal(t, 0, report.SeedA)
+	assert.Equal(t, 1, report.SeedB)
+	assert.NotEmpty(t, report.Diff)
+}
+
+func TestShouldRequireTwoSeeds(t *testing.T) {
+	sc, err := scenario.Parse(strings.NewReader(concurrentScenario))
+	assert.NoError(t, err)
+	_, err = NewChecker(100, 2).Check(sc, []int{0})
+	assert.Error(t, err)
+}
+
+// lossyNode delivers the operations reachable from the node, except the one with the lost id.
+type lossyNode struct {
+	hashgraph.Node
+	lost uuid.UUID
+}
+
+func (n *lossyNode) GetNext() []hashgraph.Node {
+	return lo.Map(n.Node.GetNext(), func(nxt hashgraph.Node, _ int) hashgraph.Node {
+		return &lossyNode{Node: nxt, lost: n.lost}
+	})
+}
+
+func (n *lossyNode) ExecFunc() error {
+	if n.GetId() == n.lost {
+		return nil
+	}
+	return n.Node.ExecFunc()
+}
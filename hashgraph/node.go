@@ -1,6 +1,8 @@
 package hashgraph
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
 	. "github.com/google/uuid"
 	"github.com/negrel/assert"
 	"github.com/samber/lo"
@@ -10,6 +12,8 @@ import (
 	"slices"
 )
 
+var contentNamespace = MustParse("3f0e8b5c-8a43-4d55-9c59-2b4c8a7c3e11")
+
 type Node interface {
 	GetId() UUID
 	GetNext() []Node
@@ -37,6 +41,11 @@ func (n *OpNode) ExecFunc() error {
 }
 
 func NewNode(op func(depth int, id UUID, prevIds []UUID) error, prev []*OpNode) *OpNode {
+	return NewNodeWithId(New(), op, prev)
+}
+
+// NewNodeWithId creates a node with a given id. Used when the same operation must be replicated at several hashgraphs.
+func NewNodeWithId(id UUID, op func(depth int, id UUID, prevIds []UUID) error, prev []*OpNode) *OpNode {
 	var depth int
 	if prev == nil {
 		prev = make([]*OpNode, 0)
@@ -44,7 +53,6 @@ func NewNode(op func(depth int, id UUID, prevIds []UUID) error, prev []*OpNode)
 	} else {
 		depth = 1 + lo.Max(lo.Map(prev, func(p *OpNode, _ int) int { return p.depth }))
 	}
-	id := New()
 	prevIds := lo.Map(prev, func(p *OpNode, _ int) UUID { return p.id })
 	n := &OpNode{
 		id:    id,
@@ -59,6 +67,28 @@ func NewNode(op func(depth int, id UUID, prevIds []UUID) error, prev []*OpNode)
 	return n
 }
 
+// NewContentNode creates a node whose id is derived from the operation payload and the ids of its predecessors.
+// The same operation issued after the same predecessors always gets the same id.
+func NewContentNode(payload []byte, op func(depth int, id UUID, prevIds []UUID) error, prev []*OpNode) *OpNode {
+	prevIds := lo.Map(prev, func(p *OpNode, _ int) UUID { return p.id })
+	return NewNodeWithId(ContentId(payload, prevIds), op, prev)
+}
+
+// ContentId hashes the payload of an operation and the ids of its predecessors into an id.
+func ContentId(payload []byte, prevIds []UUID) UUID {
+	data := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	data = append(data, payload...)
+	for _, id := range prevIds {
+		data = append(data, id[:]...)
+	}
+	return NewHash(sha256.New(), contentNamespace, data, 8)
+}
+
+// VerifyId checks that an id was derived from the payload and predecessors it is presented with.
+func VerifyId(id UUID, payload []byte, prevIds []UUID) bool {
+	return id == ContentId(payload, prevIds)
+}
+
 func (n *OpNode) addNext(nxt *OpNode) {
 	n.next = append(n.next, nxt)
 }
@@ -93,6 +123,53 @@ func RunHashgraph(seed int, n Node) {
 	}
 }
 
+// RunHashgraphCausal executes every node reachable from n, releasing a node only once all of its predecessors
+// have executed. Among the released nodes, the next one to run is picked at random using the seed.
+func RunHashgraphCausal(seed int, n Node) {
+	r := rand.New(rand.NewSource(int64(seed)))
+	pending := countPredecessors(n)
+	ready := []Node{n}
+	executed := make(map[UUID]bool)
+	for len(ready) > 0 {
+		i := r.Intn(len(ready))
+		curr := ready[i]
+		ready[i] = ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		err := curr.ExecFunc()
+		if err != nil {
+			slog.Error("Error executing operation", "err", err)
+		}
+		executed[curr.GetId()] = true
+		for _, nxtNode := range curr.GetNext() {
+			pending[nxtNode.GetId()]--
+			if pending[nxtNode.GetId()] == 0 {
+				ready = append(ready, nxtNode)
+			}
+		}
+
+		// Assertions only run if the tag "assert" is used. e.g. go run -tags assert .
+		assert.Equal(0, pending[curr.GetId()], "Operations must only execute after all their predecessors")
+		assert.True(isDisjoint(executed, ready), "No operation to be executed must have been executed before")
+	}
+}
+
+// countPredecessors returns, for each node reachable from n, how many of its predecessors are also reachable from n.
+func countPredecessors(n Node) map[UUID]int {
+	pending := map[UUID]int{n.GetId(): 0}
+	frontier := []Node{n}
+	for len(frontier) > 0 {
+		curr := frontier[0]
+		frontier = frontier[1:]
+		for _, nxtNode := range curr.GetNext() {
+			if _, visited := pending[nxtNode.GetId()]; !visited {
+				frontier = append(frontier, nxtNode)
+			}
+			pending[nxtNode.GetId()]++
+		}
+	}
+	return pending
+}
+
 func setContains(big, small map[UUID]bool) bool {
 	ids := slices.Collect(maps.Keys(small))
 	return lo.EveryBy(ids, func(id UUID) bool { return big[id] })
@@ -134,3 +134,123 @@ func TestShouldNotExecuteTwice(t *testing.T) {
 	RunHashgraph(0, firstNode)
 	assert.Equal(t, len(vals), len(executed))
 }
+
+func TestCausalShouldWaitForAllPredecessors(t *testing.T) {
+	for seed := 0; seed < 100; seed++ {
+		executed := make(map[byte]bool)
+		mergeSawParents := false
+		firstNode := NewNode(func(_ int, _ uuid.UUID, _ []uuid.UUID) error {
+			executed['A'] = true
+			return nil
+		}, nil)
+		upNode := NewNode(func(_ int, _ uuid.UUID, _ []uuid.UUID) error {
+			executed['B'] = true
+			return nil
+		}, []*OpNode{firstNode})
+		downPrev := firstNode
+		for i := 0; i < 10; i++ {
+			downPrev = NewNode(func(_ int, _ uuid.UUID, _ []uuid.UUID) error { return nil }, []*OpNode{downPrev})
+		}
+		downNode := NewNode(func(_ int, _ uuid.UUID, _ []uuid.UUID) error {
+			executed['C'] = true
+			return nil
+		}, []*OpNode{downPrev})
+		NewNode(func(_ int, _ uuid.UUID, _ []uuid.UUID) error {
+			mergeSawParents = executed['B'] && executed['C']
+			return nil
+		}, []*OpNode{upNode, downNode})
+		RunHashgraphCausal(seed, firstNode)
+		assert.True(t, mergeSawParents)
+	}
+}
+
+func TestCausalShouldNotExecuteTwice(t *testing.T) {
+	executed := make([]int, 0)
+	firstNode := NewNode(func(_ int, _ uuid.UUID, _ []uuid.UUID) error {
+		executed = append(executed, 0)
+		return nil
+	}, nil)
+	layer := []*OpNode{firstNode}
+	for i := 1; i < 10; i++ {
+		nxtLayer := make([]*OpNode, 0, len(layer))
+		for j := 0; j < 5; j++ {
+			nxtLayer = append(nxtLayer, NewNode(func(_ int, _ uuid.UUID, _ []uuid.UUID) error {
+				executed = append(executed, i)
+				return nil
+			}, layer))
+		}
+		layer = nxtLayer
+	}
+	RunHashgraphCausal(0, firstNode)
+	assert.Equal(t, 1+9*5, len(executed))
+	for i := 1; i < len(executed); i++ {
+		assert.LessOrEqual(t, executed[i-1], executed[i])
+	}
+}
+
+func TestCausalShouldProduceSameOrder(t *testing.T) {
+	numNodes := 1000
+	order1 := make([]int32, 0, numNodes)
+	order2 := make([]int32, 0, numNodes)
+	firstNode := NewNode(func(_ int, _ uuid.UUID, _ []uuid.UUID) error { return nil }, nil)
+	var order *[]int32
+	for i := 0; i < numNodes; i++ {
+		NewNode(func(_ int, _ uuid.UUID, _ []uuid.UUID) error {
+			*order = append(*order, int32(i))
+			return nil
+		}, []*OpNode{firstNode})
+	}
+	order = &order1
+	RunHashgraphCausal(0, firstNode)
+	order = &order2
+	RunHashgraphCausal(0, firstNode)
+	assert.Equal(t, numNodes, len(order1))
+	assert.Equal(t, order1, order2)
+}
+
+func TestCausalShouldProduceDifferentOrder(t *testing.T) {
+	numNodes := 1000
+	order1 := make([]int32, 0, numNodes)
+	order2 := make([]int32, 0, numNodes)
+	firstNode := NewNode(func(_ int, _ uuid.UUID, _ []uuid.UUID) error { return nil }, nil)
+	var order *[]int32
+	for i := 0; i < numNodes; i++ {
+		NewNode(func(_ int, _ uuid.UUID, _ []uuid.UUID) error {
+			*order = append(*order, int32(i))
+			return nil
+		}, []*OpNode{firstNode})
+	}
+	order = &order1
+	RunHashgraphCausal(0, firstNode)
+	order = &order2
+	RunHashgraphCausal(1, firstNode)
+	assert.Equal(t, numNodes, len(order1))
+	assert.Equal(t, numNodes, len(order2))
+	assert.NotEqual(t, order1, order2)
+}
+
+func TestContentIdShouldDependOnPayloadAndPredecessors(t *testing.T) {
+	prev := []uuid.UUID{uuid.New(), uuid.New()}
+	id := ContentId([]byte("payload"), prev)
+	assert.Equal(t, id, ContentId([]byte("payload"), prev))
+	assert.NotEqual(t, id, ContentId([]byte("payloaf"), prev))
+	assert.NotEqual(t, id, ContentId([]byte("payload"), prev[:1]))
+	assert.NotEqual(t, id, ContentId([]byte("payload"), []uuid.UUID{prev[1], prev[0]}))
+	assert.True(t, VerifyId(id, []byte("payload"), prev))
+	assert.False(t, VerifyId(id, []byte("tampered"), prev))
+}
+
+func TestShouldMakeContentNode(t *testing.T) {
+	noop := func(_ int, _ uuid.UUID, _ []uuid.UUID) error { return nil }
+	var receivedId uuid.UUID
+	first := NewContentNode([]byte("first"), noop, nil)
+	second := NewContentNode([]byte("second"), func(_ int, id uuid.UUID, _ []uuid.UUID) error {
+		receivedId = id
+		return nil
+	}, []*OpNode{first})
+	assert.Equal(t, ContentId([]byte("first"), []uuid.UUID{}), first.GetId())
+	assert.Equal(t, ContentId([]byte("second"), []uuid.UUID{first.GetId()}), second.GetId())
+	assert.Equal(t, first.GetId(), NewContentNode([]byte("first"), noop, nil).GetId())
+	RunHashgraphCausal(0, first)
+	assert.Equal(t, second.GetId(), receivedId)
+}
@@ -1,154 +1,247 @@
 package main
 
 import (
+	"bytes"
 	"dare_randomized_access_control/accesscontrolapp"
+	"dare_randomized_access_control/convergence"
 	"dare_randomized_access_control/hashgraph"
+	"dare_randomized_access_control/scenario"
+	"dare_randomized_access_control/simulator"
+	_ "embed"
+	"flag"
 	"fmt"
-	"github.com/google/uuid"
 	"github.com/inancgumus/screen"
 	"github.com/samber/lo"
 	"log/slog"
-	"math/rand"
+	"os"
 	"strings"
 	"time"
 )
 
+//go:embed scenarios/demo.scenario
+var demoScenario []byte
+
 type programExecutor struct {
 	crdt          accesscontrolapp.CRDT
-	init          *hashgraph.OpNode
+	executor      *accesscontrolapp.Executor
 	threshold     int
 	numPoints     int
 	sleepInterval time.Duration
+	// dealt holds the deals issued, so that each is issued once even if rejected
+	dealt map[accesscontrolapp.PendingDeal]bool
+	// revealed holds the coin shares issued, so that each is issued once even if rejected
+	revealed map[accesscontrolapp.PendingCoinShare]bool
 }
 
 func main() {
+	var err error
+	if len(os.Args) < 2 {
+		err = runDemo()
+	} else {
+		switch os.Args[1] {
+		case "run":
+			err = runCmd(os.Args[2:])
+		case "simulate":
+			err = simulateCmd(os.Args[2:])
+		case "check":
+			err = checkCmd(os.Args[2:])
+		default:
+			err = fmt.Errorf("unknown command %s, expected one of: run, simulate, check", os.Args[1])
+		}
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func runDemo() error {
+	sc, err := scenario.Parse(bytes.NewReader(demoScenario))
+	if err != nil {
+		return fmt.Errorf("unable to parse demo scenario: %v", err)
+	}
 	executor := &programExecutor{
 		crdt:          accesscontrolapp.NewCRDT(),
 		threshold:     2,
 		numPoints:     1000,
 		sleepInterval: 3 * time.Second,
 	}
-	err := executor.runProgram()
+	return executor.runProgram(sc)
+}
+
+func runCmd(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	numPoints := flags.Int("points", 1000, "number of points in the group")
+	threshold := flags.Int("threshold", 2, "threshold of the coin toss secret sharing")
+	sleep := flags.Duration("sleep", 3*time.Second, "time to wait between operations")
+	if err := flags.Parse(args); err != nil {
+		return err
+	} else if flags.NArg() != 1 {
+		return fmt.Errorf("usage: run [flags] <scenario file>")
+	}
+	sc, err := scenario.Load(flags.Arg(0))
 	if err != nil {
-		fmt.Println(err)
+		return err
+	}
+	executor := &programExecutor{
+		crdt:          accesscontrolapp.NewCRDT(),
+		threshold:     *threshold,
+		numPoints:     *numPoints,
+		sleepInterval: *sleep,
+	}
+	return executor.runProgram(sc)
+}
+
+func simulateCmd(args []string) error {
+	config := simulator.DefaultConfig()
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	flags.Int64Var(&config.Seed, "seed", config.Seed, "seed of the simulated network")
+	flags.IntVar(&config.MinDelay, "min-delay", config.MinDelay, "minimum message delay in ticks")
+	flags.IntVar(&config.MaxDelay, "max-delay", config.MaxDelay, "maximum message delay in ticks")
+	flags.Float64Var(&config.DropRate, "drop", config.DropRate, "probability of dropping a message")
+	flags.IntVar(&config.RetransmitTimeout, "timeout", config.RetransmitTimeout, "ticks before a dropped message is retransmitted")
+	flags.IntVar(&config.NumPoints, "points", config.NumPoints, "number of points in the group")
+	flags.IntVar(&config.Threshold, "threshold", config.Threshold, "threshold of the coin toss secret sharing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	} else if flags.NArg() != 1 {
+		return fmt.Errorf("usage: simulate [flags] <scenario file>")
+	} else if config.DropRate < 0 || config.DropRate >= 1 {
+		return fmt.Errorf("drop rate must be in [0, 1)")
+	}
+	sc, err := scenario.Load(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	slog.SetLogLoggerLevel(slog.LevelError)
+	accesscontrolapp.LogMembershipChanges = false
+	sim, err := simulator.New(sc, config)
+	if err != nil {
+		return err
+	}
+	res, err := sim.Run()
+	if err != nil {
+		return fmt.Errorf("error running simulation: %v", err)
 	}
+	fmt.Printf("%d messages sent, %d dropped, %d ticks\n", res.Sent, res.Dropped, res.Ticks)
+	for _, rep := range sim.Replicas {
+		app := res.Apps[rep.User.Alias]
+		members := lo.Map(app.Members(), func(u *accesscontrolapp.User, _ int) string {
+			return fmt.Sprintf("%s(%d)", u.PrettyName(), u.Points.Len())
+		})
+		fmt.Printf("%s: members %s, %d messages\n", rep.User.PrettyName, strings.Join(members, " "), len(app.Msgs))
+	}
+	if res.Divergence != "" {
+		return fmt.Errorf("replicas did not converge: %s", res.Divergence)
+	}
+	fmt.Println("All replicas converged")
+	return nil
 }
 
-func (pe *programExecutor) runProgram() error {
+func checkCmd(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	numSeeds := flags.Int("seeds", 10, "number of schedules to compare")
+	numPoints := flags.Int("points", 1000, "number of points in the group")
+	threshold := flags.Int("threshold", 2, "threshold of the coin toss secret sharing")
+	schedule := flags.String("schedule", "causal", "order of delivery: causal, or hashgraph to deliver operations in any order")
+	if err := flags.Parse(args); err != nil {
+		return err
+	} else if flags.NArg() != 1 {
+		return fmt.Errorf("usage: check [flags] <scenario file>")
+	}
+	schedules := map[string]func(seed int, n hashgraph.Node){
+		"causal":    hashgraph.RunHashgraphCausal,
+		"hashgraph": hashgraph.RunHashgraph,
+	}
+	run, ok := schedules[*schedule]
+	if !ok {
+		return fmt.Errorf("unknown schedule %q", *schedule)
+	}
+	sc, err := scenario.Load(flags.Arg(0))
+	if err != nil {
+		return err
+	}
 	slog.SetLogLoggerLevel(slog.LevelError)
-	r := rand.New(rand.NewSource(int64(13)))
-	alice, _ := uuid.NewRandomFromReader(r)
-	bob, _ := uuid.NewRandomFromReader(r)
-	claire, _ := uuid.NewRandomFromReader(r)
-	dillan, _ := uuid.NewRandomFromReader(r)
-	secretPlayer, _ := uuid.NewRandomFromReader(r)
-	pe.init = hashgraph.NewNode(pe.crdt.Init(alice, "Alice"), nil)
-	_ = pe.runInstruction()
-	addBob := hashgraph.NewNode(pe.crdt.Add(alice, bob, "Bob", pointRange(0, 20)), []*hashgraph.OpNode{pe.init})
-	_ = pe.runInstruction()
-	alicePost1 := hashgraph.NewNode(pe.crdt.Post(alice, "Alice: Hello Bob, I gave you 20 points please add Dillan and give him 10 points, I don't know his number :P"), []*hashgraph.OpNode{addBob})
-	_ = pe.runInstruction()
-	bobPost1 := hashgraph.NewNode(pe.crdt.Post(bob, "Bob: Aye aye captain!"), []*hashgraph.OpNode{alicePost1})
-	_ = pe.runInstruction()
-	addClaire := hashgraph.NewNode(pe.crdt.Add(alice, claire, "Claire", pointRange(20, 500)), []*hashgraph.OpNode{alicePost1})
-	_ = pe.runInstruction()
-	addDillan := hashgraph.NewNode(pe.crdt.Add(bob, dillan, "Dillan", pointRange(0, 5)), []*hashgraph.OpNode{bobPost1})
-	_ = pe.runInstruction()
-	bobPost2 := hashgraph.NewNode(pe.crdt.Post(bob, "Bob: How come Claire gets 500 points while I get 10 😠"), []*hashgraph.OpNode{addClaire, addDillan})
-	_ = pe.runInstruction()
-	alicePost2 := hashgraph.NewNode(pe.crdt.Post(alice, "Alice: 🤔 Let's see, maybe because Bob stands for Byzantine"), []*hashgraph.OpNode{bobPost2})
-	_ = pe.runInstruction()
-	clairePost1 := hashgraph.NewNode(pe.crdt.Post(claire, "Claire: And Claire stands for Correct 😊"), []*hashgraph.OpNode{alicePost2})
-	_ = pe.runInstruction()
-	dillanPost1 := hashgraph.NewNode(pe.crdt.Post(dillan, "Dillan: I think Bob the byzantine menace owes me 5 points"), []*hashgraph.OpNode{clairePost1})
-	_ = pe.runInstruction()
-	bobPost3 := hashgraph.NewNode(pe.crdt.Post(bob, "Bob: ... Changing topics, wasn't a message reordered up there"), []*hashgraph.OpNode{dillanPost1})
-	_ = pe.runInstruction()
-	clairePost2 := hashgraph.NewNode(pe.crdt.Post(claire, "Claire: Pedro programmed this, it's a miracle we're even part of the demo"), []*hashgraph.OpNode{bobPost3})
-	_ = pe.runInstruction()
-	alicePost3 := hashgraph.NewNode(pe.crdt.Post(alice, "Alice: 😂 Should we just add a bunch of users until we reach P?"), []*hashgraph.OpNode{clairePost2})
-	_ = pe.runInstruction()
-	alicePost4 := hashgraph.NewNode(pe.crdt.Post(alice, "Alice: Then we can question him?"), []*hashgraph.OpNode{alicePost3})
-	_ = pe.runInstruction()
-	dillanPost2 := hashgraph.NewNode(pe.crdt.Post(dillan, "Dillan: Guys I think my net is kinda weird!"), []*hashgraph.OpNode{dillanPost1})
-	_ = pe.runInstruction()
-	dillanPost3 := hashgraph.NewNode(pe.crdt.Post(dillan, "Dillan: Can you see my messages???"), []*hashgraph.OpNode{dillanPost2})
-	_ = pe.runInstruction()
-	dillanPost4 := hashgraph.NewNode(pe.crdt.Post(dillan, "Dillan: Hellooo!! I'm all alone in the void 😭"), []*hashgraph.OpNode{dillanPost3})
-	_ = pe.runInstruction()
-	clairePost3 := hashgraph.NewNode(pe.crdt.Post(claire, "Claire: Hey Dillan! We read you loud and clear"), []*hashgraph.OpNode{dillanPost4, alicePost4})
-	_ = pe.runInstruction()
-	alicePost5 := hashgraph.NewNode(pe.crdt.Post(alice, "Alice: I guess Dillan stands for disconnected"), []*hashgraph.OpNode{clairePost3})
-	_ = pe.runInstruction()
-	bobPost4 := hashgraph.NewNode(pe.crdt.Post(bob, "Bob: Enough lollygag! The demo demands we get mad at each other 😠"), []*hashgraph.OpNode{alicePost5})
-	_ = pe.runInstruction()
-	alicePost6 := hashgraph.NewNode(pe.crdt.Post(alice, "Alice: Say no more 😈"), []*hashgraph.OpNode{bobPost4})
-	_ = pe.runInstruction()
-	remDilanAlice := hashgraph.NewNode(pe.crdt.Rem(dillan, alice), []*hashgraph.OpNode{alicePost6})
-	_ = pe.runInstruction()
-	dillanPost5 := hashgraph.NewNode(pe.crdt.Post(dillan, "Dillan: Hee Hee, got her first 😎"), []*hashgraph.OpNode{remDilanAlice})
-	_ = pe.runInstruction()
-	bobPost5 := hashgraph.NewNode(pe.crdt.Post(bob, "Bob: Good job bro!"), []*hashgraph.OpNode{dillanPost5})
-	_ = pe.runInstruction()
-	remAliceDilan := hashgraph.NewNode(pe.crdt.Rem(alice, dillan), []*hashgraph.OpNode{alicePost6})
-	_ = pe.runInstruction()
-	alicePost7 := hashgraph.NewNode(pe.crdt.Post(alice, "Alice: I'm back"), []*hashgraph.OpNode{bobPost5, remAliceDilan})
-	_ = pe.runInstruction()
-	clairePost4 := hashgraph.NewNode(pe.crdt.Post(claire, "Claire: I think she time traveled"), []*hashgraph.OpNode{alicePost7})
-	_ = pe.runInstruction()
-	bobPost6 := hashgraph.NewNode(pe.crdt.Post(bob, "Bob: Two of us can play that game. I'll save you Dillan"), []*hashgraph.OpNode{clairePost4})
-	_ = pe.runInstruction()
-	remBobAlice := hashgraph.NewNode(pe.crdt.Rem(bob, alice), []*hashgraph.OpNode{bobPost4})
-	_ = pe.runInstruction()
-	clairePost5 := hashgraph.NewNode(pe.crdt.Post(claire, "Claire: At least now we know Alice stands for A****le"), []*hashgraph.OpNode{remBobAlice, bobPost6})
-	_ = pe.runInstruction()
-	remAliceBob := hashgraph.NewNode(pe.crdt.Rem(alice, bob), []*hashgraph.OpNode{bobPost4})
-	_ = pe.runInstruction()
-	alicePost8 := hashgraph.NewNode(pe.crdt.Post(alice, "Alice: Want to remove me with those meager 10... wait, 15 points?"), []*hashgraph.OpNode{remAliceBob, clairePost5})
-	_ = pe.runInstruction()
-	clairePost6 := hashgraph.NewNode(pe.crdt.Post(claire, "Claire: Only way to beat her is to go back to the start."), []*hashgraph.OpNode{alicePost8})
-	_ = pe.runInstruction()
-	alicePost9 := hashgraph.NewNode(pe.crdt.Post(alice, "Alice: 50/50 chance, let's do it!!!!"), []*hashgraph.OpNode{clairePost6})
-	_ = pe.runInstruction()
-	remAliceClaire := hashgraph.NewNode(pe.crdt.Rem(alice, claire), []*hashgraph.OpNode{addClaire})
-	_ = pe.runInstruction()
-	remClaireAlice := hashgraph.NewNode(pe.crdt.Rem(claire, alice), []*hashgraph.OpNode{addClaire})
-	_ = pe.runInstruction()
-	bobPost7 := hashgraph.NewNode(pe.crdt.Post(bob, "Bob: Wow, that was close! 😵"), []*hashgraph.OpNode{remAliceClaire, remClaireAlice, alicePost9})
-	_ = pe.runInstruction()
-	clairePost7 := hashgraph.NewNode(pe.crdt.Post(claire, "Claire: Not really actually, Pedro controls the seed that decides our ids."), []*hashgraph.OpNode{bobPost7})
-	_ = pe.runInstruction()
-	dillanPost6 := hashgraph.NewNode(pe.crdt.Post(dillan, "Dillan: He just reruns the simulation until we win"), []*hashgraph.OpNode{clairePost7})
-	_ = pe.runInstruction()
-	bobPost8 := hashgraph.NewNode(pe.crdt.Post(bob, "Bob: And now?"), []*hashgraph.OpNode{dillanPost6})
-	_ = pe.runInstruction()
-	clairePost8 := hashgraph.NewNode(pe.crdt.Post(claire, "Claire: Now we rest Bob the brash"), []*hashgraph.OpNode{bobPost8})
-	_ = pe.runInstruction()
-	bobPost9 := hashgraph.NewNode(pe.crdt.Post(bob, "Bob: I hope they remember us fondly 😰"), []*hashgraph.OpNode{clairePost8})
-	_ = pe.runInstruction()
-	addClairePedro := hashgraph.NewNode(pe.crdt.Add(claire, secretPlayer, "Pedro", pointRange(100, 1)), []*hashgraph.OpNode{bobPost9})
-	_ = pe.runInstruction()
-	_ = hashgraph.NewNode(pe.crdt.Post(secretPlayer, "Pedro: I'm sure they will Bob. You were all truly wonderful"), []*hashgraph.OpNode{addClairePedro})
-	_ = pe.runInstruction()
+	checker := convergence.NewChecker(*numPoints, *threshold)
+	checker.Schedule = run
+	report, err := checker.Check(sc, lo.Range(*numSeeds))
+	if err != nil {
+		return fmt.Errorf("error checking convergence: %v", err)
+	} else if !report.Converged {
+		return fmt.Errorf("seeds %d and %d diverge after operations [%s]: %s", report.SeedA, report.SeedB, strings.Join(report.Prefix, " "), report.Diff)
+	}
+	fmt.Printf("All %d schedules converged\n", *numSeeds)
+	return nil
+}
+
+func (pe *programExecutor) runProgram(sc *scenario.Scenario) error {
+	slog.SetLogLoggerLevel(slog.LevelError)
+	replayer := sc.NewReplayer(&pe.crdt)
+	pe.executor = accesscontrolapp.NewExecutor(&pe.crdt, pe.numPoints, pe.threshold)
+	pe.dealt = make(map[accesscontrolapp.PendingDeal]bool)
+	pe.revealed = make(map[accesscontrolapp.PendingCoinShare]bool)
+	for node := replayer.Step(); node != nil; node = replayer.Step() {
+		if err := pe.runInstruction(replayer, node); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (pe *programExecutor) runInstruction() error {
-	seed := int(time.Now().UnixNano())
-	hashgraph.RunHashgraph(seed, pe.init)
-	app, err := accesscontrolapp.ExecuteCRDT(&pe.crdt, pe.numPoints, pe.threshold)
+// runInstruction delivers the operation of the node, whose predecessors were delivered before, and updates the app
+// with it.
+func (pe *programExecutor) runInstruction(replayer *scenario.Replayer, node *hashgraph.OpNode) error {
+	if err := node.ExecFunc(); err != nil {
+		slog.Error("Error executing operation", "err", err)
+	}
+	changed, err := pe.executor.Update()
 	if err != nil {
 		return fmt.Errorf("error executing CRDT: %v", err)
 	}
-	msgs := lo.Map(app.Msgs, func(m accesscontrolapp.Msg, _ int) string { return m.Content })
-	screen.Clear()
-	screen.MoveTopLeft()
-	fmt.Println(strings.Join(msgs, "\n"))
-	pe.crdt.Clear()
+	issuedChanged, err := pe.issuePending(replayer)
+	if err != nil {
+		return err
+	}
+	changed |= issuedChanged
+	if changed.Has(accesscontrolapp.PartMsgs) {
+		msgs := lo.Map(pe.executor.App().Msgs, func(m accesscontrolapp.Msg, _ int) string { return m.Text() })
+		screen.Clear()
+		screen.MoveTopLeft()
+		fmt.Println(strings.Join(msgs, "\n"))
+	}
 	time.Sleep(pe.sleepInterval)
 	return nil
 }
 
-func pointRange(first, num int) []uint {
-	return lo.Map(lo.Range(num), func(i int, _ int) uint { return uint(first + i) })
+// issuePending issues the deals the committee members owe to the refreshes and the coin shares the owners owe to the
+// pending coin tosses, as the demo plays every user, and updates the app with them until none is owed.
+func (pe *programExecutor) issuePending(replayer *scenario.Replayer) (accesscontrolapp.StatePart, error) {
+	var changed accesscontrolapp.StatePart
+	for {
+		app := pe.executor.App()
+		pending := lo.Filter(app.PendingDeals(), func(deal accesscontrolapp.PendingDeal, _ int) bool { return !pe.dealt[deal] })
+		pendingShares := lo.Filter(app.PendingCoinShares(), func(share accesscontrolapp.PendingCoinShare, _ int) bool { return !pe.revealed[share] })
+		if len(pending) == 0 && len(pendingShares) == 0 {
+			return changed, nil
+		}
+		for _, deal := range pending {
+			pe.dealt[deal] = true
+			node := replayer.Issue(pe.crdt.Deal(deal.Dealer, deal.Refresh, app))
+			if err := node.ExecFunc(); err != nil {
+				slog.Error("Error executing deal", "err", err)
+			}
+		}
+		for _, share := range pendingShares {
+			pe.revealed[share] = true
+			node := replayer.Issue(pe.crdt.RevealCoinShares(share.Owner, share.Seed, app))
+			if err := node.ExecFunc(); err != nil {
+				slog.Error("Error executing coin share", "err", err)
+			}
+		}
+		parts, err := pe.executor.Update()
+		if err != nil {
+			return changed, fmt.Errorf("error executing CRDT: %v", err)
+		}
+		changed |= parts
+	}
 }
@@ -0,0 +1,137 @@
+package scenario
+
+import (
+	"crypto/ed25519"
+	"dare_randomized_access_control/accesscontrolapp"
+	"dare_randomized_access_control/cointoss"
+	"dare_randomized_access_control/hashgraph"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"slices"
+)
+
+// Replayer adds the operations of a scenario to a hashgraph, one at a time and in declaration order.
+type Replayer struct {
+	// ContentIds derives the id of each operation from its payload and predecessors instead of drawing it at random.
+	ContentIds bool
+	scenario   *Scenario
+	crdt       *accesscontrolapp.CRDT
+	nodes      map[string]*hashgraph.OpNode
+	// tips holds the nodes added without successors yet
+	tips []*hashgraph.OpNode
+	next int
+}
+
+// NewReplayer registers the keys of every user in the CRDT, so that the operations they issue are signed.
+func (s *Scenario) NewReplayer(crdt *accesscontrolapp.CRDT) *Replayer {
+	for _, user := range s.Users {
+		crdt.SetKey(user.Id, user.Key)
+	}
+	return &Replayer{
+		scenario: s,
+		crdt:     crdt,
+		nodes:    make(map[string]*hashgraph.OpNode),
+		tips:     make([]*hashgraph.OpNode, 0),
+		next:     0,
+	}
+}
+
+// Build adds every operation of the scenario to a hashgraph and returns its initial node.
+// Operation ids are derived from their content, so building a scenario twice yields the same hashgraph.
+func (s *Scenario) Build(crdt *accesscontrolapp.CRDT) *hashgraph.OpNode {
+	rp := s.NewReplayer(crdt)
+	rp.ContentIds = true
+	for rp.Step() != nil {
+	}
+	return rp.Root()
+}
+
+// Step adds the next operation to the hashgraph. Returns nil once all operations have been added.
+func (rp *Replayer) Step() *hashgraph.OpNode {
+	if rp.Done() {
+		return nil
+	}
+	op := rp.scenario.Ops[rp.next]
+	prev := make([]*hashgraph.OpNode, 0, len(op.After))
+	for _, p := range op.After {
+		prev = append(prev, rp.nodes[p])
+	}
+	var node *hashgraph.OpNode
+	if rp.ContentIds {
+		node = hashgraph.NewContentNode(rp.scenario.Payload(op), rp.scenario.Bind(rp.crdt, op), prev)
+	} else {
+		node = hashgraph.NewNode(rp.scenario.Bind(rp.crdt, op), prev)
+	}
+	rp.nodes[op.Name] = node
+	rp.addTip(node, prev)
+	rp.next++
+	return node
+}
+
+// Issue adds an operation outside the scenario to the hashgraph, after every node added so far without successors.
+// The operations of the scenario added later do not follow it.
+func (rp *Replayer) Issue(op func(depth int, id uuid.UUID, prevIds []uuid.UUID) error) *hashgraph.OpNode {
+	prev := slices.Clone(rp.tips)
+	node := hashgraph.NewNode(op, prev)
+	rp.addTip(node, prev)
+	return node
+}
+
+func (rp *Replayer) addTip(node *hashgraph.OpNode, prev []*hashgraph.OpNode) {
+	rp.tips = append(lo.Without(rp.tips, prev...), node)
+}
+
+func (rp *Replayer) Done() bool {
+	return rp.next >= len(rp.scenario.Ops)
+}
+
+func (rp *Replayer) Root() *hashgraph.OpNode {
+	if len(rp.scenario.Ops) == 0 {
+		return nil
+	}
+	return rp.nodes[rp.scenario.Ops[0].Name]
+}
+
+func (rp *Replayer) Node(name string) *hashgraph.OpNode {
+	return rp.nodes[name]
+}
+
+// Bind turns the operation into the function the hashgraph executes to deliver it to the CRDT.
+func (s *Scenario) Bind(crdt *accesscontrolapp.CRDT, op *Op) func(depth int, id uuid.UUID, prevIds []uuid.UUID) error {
+	issuer := s.users[op.Issuer]
+	switch op.Kind {
+	case Init:
+		return crdt.Init(issuer.Id, issuer.PrettyName)
+	case Post:
+		return crdt.Post(issuer.Id, op.Msg)
+	case Add:
+		added := s.users[op.Target]
+		return crdt.Add(issuer.Id, added.Id, added.PrettyName, op.Points)
+	default:
+		return crdt.Rem(issuer.Id, s.users[op.Target].Id)
+	}
+}
+
+// Payload returns the canonical encoding of the operation content.
+func (s *Scenario) Payload(op *Op) []byte {
+	issuer := s.users[op.Issuer]
+	switch op.Kind {
+	case Init:
+		return accesscontrolapp.InitPayload(issuer.Id, issuer.PrettyName, issuer.PublicKey(), issuer.ShareKey().Public)
+	case Post:
+		return accesscontrolapp.PostPayload(issuer.Id, op.Msg)
+	case Add:
+		added := s.users[op.Target]
+		return accesscontrolapp.AddPayload(issuer.Id, added.Id, added.PrettyName, added.PublicKey(), added.ShareKey().Public, op.Points)
+	default:
+		return accesscontrolapp.RemPayload(issuer.Id, s.users[op.Target].Id)
+	}
+}
+
+func (u *User) PublicKey() ed25519.PublicKey {
+	return u.Key.Public().(ed25519.PublicKey)
+}
+
+func (u *User) ShareKey() cointoss.ShareKey {
+	return accesscontrolapp.ShareKeyFromSigningKey(u.Key)
+}
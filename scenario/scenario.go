@@ -0,0 +1,282 @@
+// Package scenario loads conversations for the access control app from text files.
+//
+// A scenario file describes a conversation as a list of declarations, one per line.
+// Blank lines and lines starting with '#' are ignored. Strings containing spaces must be double-quoted.
+//
+//	seed 13
+//	user alice Alice
+//	user bob Bob
+//	init start alice
+//	add addBob alice bob 0..20 after start
+//	post hello alice "Alice: Hello Bob" after addBob
+//	rem kick bob alice after hello
+//
+// User ids, followed by their signing keys, are drawn in declaration order from a random source seeded with the scenario seed.
+// Points are given as comma separated values or half-open ranges, e.g. "0..20,25".
+// Operations may only reference operations declared before them.
+package scenario
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type OpKind string
+
+const (
+	Init OpKind = "init"
+	Post OpKind = "post"
+	Add  OpKind = "add"
+	Rem  OpKind = "rem"
+)
+
+type User struct {
+	Alias      string
+	PrettyName string
+	Id         uuid.UUID
+	Key        ed25519.PrivateKey
+}
+
+type Op struct {
+	Name   string
+	Kind   OpKind
+	Issuer string
+	Target string
+	Points []uint
+	Msg    string
+	After  []string
+}
+
+type Scenario struct {
+	Seed  int64
+	Users []*User
+	Ops   []*Op
+	users map[string]*User
+	ops   map[string]*Op
+}
+
+func Load(path string) (*Scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open scenario file: %v", err)
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+func Parse(r io.Reader) (*Scenario, error) {
+	s := &Scenario{
+		Users: make([]*User, 0),
+		Ops:   make([]*Op, 0),
+		users: make(map[string]*User),
+		ops:   make(map[string]*Op),
+	}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tokens, err := tokenize(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		switch tokens[0] {
+		case "seed":
+			if len(tokens) != 2 {
+				return nil, fmt.Errorf("line %d: expected 'seed <value>'", lineNum)
+			}
+			if s.Seed, err = strconv.ParseInt(tokens[1], 10, 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid seed: %v", lineNum, err)
+			}
+		case "user":
+			if len(tokens) != 3 {
+				return nil, fmt.Errorf("line %d: expected 'user <alias> <name>'", lineNum)
+			} else if s.users[tokens[1]] != nil {
+				return nil, fmt.Errorf("line %d: user %s declared twice", lineNum, tokens[1])
+			}
+			user := &User{Alias: tokens[1], PrettyName: tokens[2]}
+			s.users[user.Alias] = user
+			s.Users = append(s.Users, user)
+		default:
+			op, err := s.parseOp(tokens)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNum, err)
+			}
+			s.ops[op.Name] = op
+			s.Ops = append(s.Ops, op)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read scenario: %v", err)
+	}
+	if err := s.assignIds(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Scenario) assignIds() error {
+	r := rand.New(rand.NewSource(s.Seed))
+	for _, user := range s.Users {
+		id, err := uuid.NewRandomFromReader(r)
+		if err != nil {
+			return fmt.Errorf("unable to generate id for user %s: %v", user.Alias, err)
+		}
+		user.Id = id
+	}
+	for _, user := range s.Users {
+		seed := make([]byte, ed25519.SeedSize)
+		if _, err := r.Read(seed); err != nil {
+			return fmt.Errorf("unable to generate key for user %s: %v", user.Alias, err)
+		}
+		user.Key = ed25519.NewKeyFromSeed(seed)
+	}
+	return nil
+}
+
+func (s *Scenario) parseOp(tokens []string) (*Op, error) {
+	if len(tokens) < 3 {
+		return nil, fmt.Errorf("expected '<kind> <name> <issuer> ...'")
+	}
+	op := &Op{
+		Name:   tokens[1],
+		Kind:   OpKind(tokens[0]),
+		Issuer: tokens[2],
+		After:  make([]string, 0),
+	}
+	if s.ops[op.Name] != nil {
+		return nil, fmt.Errorf("operation %s declared twice", op.Name)
+	} else if s.users[op.Issuer] == nil {
+		return nil, fmt.Errorf("unknown issuer %s", op.Issuer)
+	}
+	args := tokens[3:]
+	for i, tk := range args {
+		if tk == "after" {
+			op.After = args[i+1:]
+			args = args[:i]
+			break
+		}
+	}
+	for _, p := range op.After {
+		if s.ops[p] == nil {
+			return nil, fmt.Errorf("unknown previous operation %s", p)
+		}
+	}
+	var err error
+	switch op.Kind {
+	case Init:
+		if len(args) != 0 || len(op.After) != 0 {
+			return nil, fmt.Errorf("expected 'init <name> <issuer>'")
+		} else if len(s.Ops) != 0 {
+			return nil, fmt.Errorf("init must be the first operation")
+		}
+	case Post:
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected 'post <name> <issuer> <msg> after <ops>'")
+		}
+		op.Msg = args[0]
+	case Add:
+		if len(args) != 2 {
+			return nil, fmt.Errorf("expected 'add <name> <issuer> <added> <points> after <ops>'")
+		}
+		op.Target = args[0]
+		if op.Points, err = parsePoints(args[1]); err != nil {
+			return nil, err
+		}
+	case Rem:
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected 'rem <name> <issuer> <removed> after <ops>'")
+		}
+		op.Target = args[0]
+	default:
+		return nil, fmt.Errorf("unknown operation kind %s", op.Kind)
+	}
+	if op.Kind != Init && len(op.After) == 0 {
+		return nil, fmt.Errorf("operation %s must come after at least one operation", op.Name)
+	} else if op.Kind != Init && len(s.Ops) == 0 {
+		return nil, fmt.Errorf("the first operation must be an init")
+	} else if op.Target != "" && s.users[op.Target] == nil {
+		return nil, fmt.Errorf("unknown user %s", op.Target)
+	}
+	return op, nil
+}
+
+func (s *Scenario) User(alias string) *User {
+	return s.users[alias]
+}
+
+func (s *Scenario) Op(name string) *Op {
+	return s.ops[name]
+}
+
+func parsePoints(spec string) ([]uint, error) {
+	points := make([]uint, 0)
+	for _, part := range strings.Split(spec, ",") {
+		first, last, isRange := strings.Cut(part, "..")
+		start, err := strconv.ParseUint(first, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid point %s: %v", first, err)
+		}
+		if !isRange {
+			points = append(points, uint(start))
+			continue
+		}
+		end, err := strconv.ParseUint(last, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid point %s: %v", last, err)
+		}
+		for p := start; p < end; p++ {
+			points = append(points, uint(p))
+		}
+	}
+	return points, nil
+}
+
+func tokenize(line string) ([]string, error) {
+	tokens := make([]string, 0)
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+		if line[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted string: %v", err)
+			}
+			tk, _ := strconv.Unquote(quoted)
+			tokens = append(tokens, tk)
+			line = line[len(quoted):]
+		} else {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			tokens = append(tokens, line[:end])
+			line = line[end:]
+		}
+	}
+	return tokens, nil
+}
+
+// Prefix returns a scenario with only the first n operations. As operations only come after operations declared
+// before them, every prefix is causally closed.
+func (s *Scenario) Prefix(n int) *Scenario {
+	prefix := &Scenario{
+		Seed:  s.Seed,
+		Users: s.Users,
+		Ops:   s.Ops[:n],
+		users: s.users,
+		ops:   make(map[string]*Op),
+	}
+	for _, op := range prefix.Ops {
+		prefix.ops[op.Name] = op
+	}
+	return prefix
+}
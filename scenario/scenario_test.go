@@ -0,0 +1,136 @@
+package scenario
+
+import (
+	"dare_randomized_access_control/accesscontrolapp"
+	"dare_randomized_access_control/hashgraph"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+const simpleScenario = `
+# comment
+seed 0
+user alice Alice
+user bob "Bob the builder"
+init start alice
+add addBob alice bob 0..10,20 after start
+post hello bob "Bob: Hello, Alice" after addBob
+rem kick bob alice after hello
+`
+
+func TestShouldParseScenario(t *testing.T) {
+	sc, err := Parse(strings.NewReader(simpleScenario))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(sc.Users))
+	assert.Equal(t, "Bob the builder", sc.User("bob").PrettyName)
+	assert.Equal(t, 4, len(sc.Ops))
+	assert.Equal(t, Add, sc.Op("addBob").Kind)
+	assert.Equal(t, append(lo.Map(lo.Range(10), func(i, _ int) uint { return uint(i) }), 20), sc.Op("addBob").Points)
+	assert.Equal(t, "Bob: Hello, Alice", sc.Op("hello").Msg)
+	assert.Equal(t, []string{"hello"}, sc.Op("kick").After)
+}
+
+func TestShouldDrawUserIdsFromSeed(t *testing.T) {
+	sc, err := Parse(strings.NewReader(simpleScenario))
+	assert.NoError(t, err)
+	r := rand.New(rand.NewSource(int64(0)))
+	for _, user := range sc.Users {
+		id, err := uuid.NewRandomFromReader(r)
+		assert.NoError(t, err)
+		assert.Equal(t, id, user.Id)
+	}
+}
+
+func TestShouldRejectInvalidScenarios(t *testing.T) {
+	invalid := []string{
+		"user alice Alice\npost p alice \"hi\"",
+		"user alice Alice\ninit start alice\npost p alice \"hi\"",
+		"user alice Alice\ninit start alice\npost p alice \"hi\" after missing",
+		"user alice Alice\ninit start alice\nadd a alice bob 0..1 after start",
+		"user alice Alice\ninit start alice\ninit start2 alice",
+		"user alice Alice\ninit start alice\npost start alice \"hi\" after start",
+		"user alice Alice\nuser alice Alice2",
+		"user alice Alice\ninit start alice\nadd a alice alice x..1 after start",
+		"user alice Alice\ninit start alice\nfly a alice after start",
+		"user alice Alice\ninit start alice\npost p alice \"unterminated after start",
+	}
+	for _, s := range invalid {
+		_, err := Parse(strings.NewReader(s))
+		assert.Error(t, err, s)
+	}
+}
+
+func TestShouldReplayScenario(t *testing.T) {
+	accesscontrolapp.LogMembershipChanges = false
+	sc, err := Parse(strings.NewReader(simpleScenario))
+	assert.NoError(t, err)
+	crdt := accesscontrolapp.NewCRDT()
+	rp := sc.NewReplayer(&crdt)
+	steps := 0
+	for rp.Step() != nil {
+		steps++
+	}
+	assert.True(t, rp.Done())
+	assert.Equal(t, len(sc.Ops), steps)
+	hashgraph.RunHashgraphCausal(0, rp.Root())
+	app, err := accesscontrolapp.ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(app.Msgs))
+	assert.Equal(t, sc.User("bob").Id, app.Msgs[0].Issuer)
+}
+
+func TestShouldLoadDemoScenario(t *testing.T) {
+	accesscontrolapp.LogMembershipChanges = false
+	sc, err := Load("../scenarios/demo.scenario")
+	assert.NoError(t, err)
+	crdt := accesscontrolapp.NewCRDT()
+	root := sc.Build(&crdt)
+	hashgraph.RunHashgraphCausal(0, root)
+	app, err := accesscontrolapp.ExecuteCRDT(&crdt, 1000, 2)
+	assert.NoError(t, err)
+	numPosts := len(lo.Filter(sc.Ops, func(op *Op, _ int) bool { return op.Kind == Post }))
+	assert.LessOrEqual(t, len(app.Msgs), numPosts)
+	assert.NotEmpty(t, app.Msgs)
+}
+
+func TestShouldDeriveIdsFromContent(t *testing.T) {
+	sc, err := Parse(strings.NewReader(simpleScenario))
+	assert.NoError(t, err)
+	ids := make([][]uuid.UUID, 0, 2)
+	for i := 0; i < 2; i++ {
+		crdt := accesscontrolapp.NewCRDT()
+		rp := sc.NewReplayer(&crdt)
+		rp.ContentIds = true
+		for rp.Step() != nil {
+		}
+		ids = append(ids, lo.Map(sc.Ops, func(op *Op, _ int) uuid.UUID { return rp.Node(op.Name).GetId() }))
+	}
+	assert.Equal(t, ids[0], ids[1])
+	assert.Equal(t, hashgraph.ContentId(sc.Payload(sc.Op("start")), []uuid.UUID{}), ids[0][0])
+}
+
+func TestShouldIssueAfterTips(t *testing.T) {
+	accesscontrolapp.LogMembershipChanges = false
+	sc, err := Parse(strings.NewReader(simpleScenario))
+	assert.NoError(t, err)
+	crdt := accesscontrolapp.NewCRDT()
+	rp := sc.NewReplayer(&crdt)
+	rp.Step()
+	addBob := rp.Step()
+	first := rp.Issue(crdt.Post(sc.User("alice").Id, "first"))
+	hello := rp.Step()
+	second := rp.Issue(crdt.Post(sc.User("bob").Id, "second"))
+	rp.Step()
+	assert.ElementsMatch(t, []hashgraph.Node{first, hello}, addBob.GetNext())
+	assert.Equal(t, []hashgraph.Node{second}, first.GetNext())
+	assert.Contains(t, hello.GetNext(), second)
+	assert.Empty(t, second.GetNext())
+	hashgraph.RunHashgraphCausal(0, rp.Root())
+	app, err := accesscontrolapp.ExecuteCRDT(&crdt, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(app.Msgs))
+}
@@ -0,0 +1,90 @@
+package simulator
+
+import (
+	"container/heap"
+	"math/rand"
+)
+
+// network delivers envelopes between replicas after a random delay.
+// Dropped envelopes are retransmitted by the sender after a timeout, so every envelope is eventually delivered.
+type network struct {
+	r       *rand.Rand
+	config  Config
+	now     int
+	seq     int
+	events  eventQueue
+	sent    int
+	dropped int
+}
+
+type event struct {
+	time     int
+	seq      int
+	envelope *envelope
+	to       *Replica
+}
+
+type eventQueue []*event
+
+func newNetwork(r *rand.Rand, config Config) *network {
+	return &network{
+		r:      r,
+		config: config,
+		events: make(eventQueue, 0),
+	}
+}
+
+func (net *network) send(env *envelope, to *Replica) {
+	net.sent++
+	delay := net.config.MinDelay
+	if net.config.MaxDelay > net.config.MinDelay {
+		delay += net.r.Intn(net.config.MaxDelay - net.config.MinDelay + 1)
+	}
+	for net.r.Float64() < net.config.DropRate {
+		net.dropped++
+		delay += net.config.RetransmitTimeout
+	}
+	net.seq++
+	heap.Push(&net.events, &event{
+		time:     net.now + delay,
+		seq:      net.seq,
+		envelope: env,
+		to:       to,
+	})
+}
+
+// next advances the simulated clock to the following delivery. Returns nil when nothing is in transit.
+func (net *network) next() *event {
+	if net.events.Len() == 0 {
+		return nil
+	}
+	ev := heap.Pop(&net.events).(*event)
+	net.now = ev.time
+	return ev
+}
+
+func (q eventQueue) Len() int {
+	return len(q)
+}
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].time == q[j].time {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].time < q[j].time
+}
+
+func (q eventQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *eventQueue) Push(x any) {
+	*q = append(*q, x.(*event))
+}
+
+func (q *eventQueue) Pop() any {
+	old := *q
+	ev := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ev
+}
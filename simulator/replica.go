@@ -0,0 +1,186 @@
+package simulator
+
+import (
+	"dare_randomized_access_control/accesscontrolapp"
+	"dare_randomized_access_control/hashgraph"
+	"dare_randomized_access_control/scenario"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"log/slog"
+	"slices"
+)
+
+// Replica is the view of a single participant. It keeps its own copy of the hashgraph and of the CRDT.
+type Replica struct {
+	User      *scenario.User
+	scenario  *scenario.Scenario
+	crdt      accesscontrolapp.CRDT
+	nodes     map[uuid.UUID]*hashgraph.OpNode
+	root      *hashgraph.OpNode
+	pending   []*envelope
+	Delivered []string
+	// Rejected holds the ids of received operations whose content did not match their id.
+	Rejected []uuid.UUID
+	// Issued holds the ids of the deals and coin shares the participant issued. They are not listed in Delivered.
+	Issued []uuid.UUID
+	// tips holds the ids of the operations delivered without successors yet
+	tips []uuid.UUID
+	// stale is set when operations were delivered since the participant last looked for what it owes
+	stale bool
+	// dealt holds the deals issued, so that each is issued once even if rejected
+	dealt map[accesscontrolapp.PendingDeal]bool
+	// revealed holds the coin shares issued, so that each is issued once even if rejected
+	revealed map[accesscontrolapp.PendingCoinShare]bool
+}
+
+// envelope carries an operation between replicas. Deals and coin shares, which are not part of the scenario, are
+// carried as their payload.
+type envelope struct {
+	op      *scenario.Op
+	coinOp  []byte
+	id      uuid.UUID
+	prevIds []uuid.UUID
+	sig     []byte
+}
+
+// newReplica creates the replica of a participant, which knows the public keys and public share keys of every other
+// participant, and its own share key.
+func newReplica(sc *scenario.Scenario, user *scenario.User) *Replica {
+	crdt := accesscontrolapp.NewCRDT()
+	for _, u := range sc.Users {
+		crdt.SetPublicKey(u.Id, u.PublicKey())
+		crdt.SetSharePublicKey(u.Id, u.ShareKey().Public)
+	}
+	crdt.SetShareKey(user.Id, user.ShareKey())
+	return &Replica{
+		User:      user,
+		scenario:  sc,
+		crdt:      crdt,
+		nodes:     make(map[uuid.UUID]*hashgraph.OpNode),
+		pending:   make([]*envelope, 0),
+		Delivered: make([]string, 0),
+		Rejected:  make([]uuid.UUID, 0),
+		Issued:    make([]uuid.UUID, 0),
+		tips:      make([]uuid.UUID, 0),
+		dealt:     make(map[accesscontrolapp.PendingDeal]bool),
+		revealed:  make(map[accesscontrolapp.PendingCoinShare]bool),
+	}
+}
+
+// payload returns the canonical encoding of the operation carried.
+func (env *envelope) payload(sc *scenario.Scenario) []byte {
+	if env.op == nil {
+		return env.coinOp
+	}
+	return sc.Payload(env.op)
+}
+
+// name returns the name of the operation carried in the scenario.
+func (env *envelope) name() string {
+	if env.op == nil {
+		return "coin operation"
+	}
+	return env.op.Name
+}
+
+func (rep *Replica) has(id uuid.UUID) bool {
+	return rep.nodes[id] != nil
+}
+
+// receive buffers the envelope until all the operations it depends on have been delivered.
+// Envelopes carrying an operation already received are ignored, and those whose id does not match their content are rejected.
+func (rep *Replica) receive(env *envelope) {
+	if rep.has(env.id) || lo.ContainsBy(rep.pending, func(p *envelope) bool { return p.id == env.id }) {
+		return
+	} else if !hashgraph.VerifyId(env.id, env.payload(rep.scenario), env.prevIds) {
+		slog.Warn("Rejected operation not matching its id", "replica", rep.User.Alias, "id", env.id, "op", env.name())
+		rep.Rejected = append(rep.Rejected, env.id)
+		return
+	}
+	rep.pending = append(rep.pending, env)
+	for delivered := true; delivered; {
+		delivered = false
+		for i, p := range rep.pending {
+			if lo.EveryBy(p.prevIds, rep.has) {
+				rep.pending = append(rep.pending[:i], rep.pending[i+1:]...)
+				rep.deliver(p)
+				delivered = true
+				break
+			}
+		}
+	}
+}
+
+func (rep *Replica) deliver(env *envelope) {
+	prev := lo.Map(env.prevIds, func(id uuid.UUID, _ int) *hashgraph.OpNode { return rep.nodes[id] })
+	if len(prev) == 0 {
+		prev = nil
+	}
+	rep.crdt.AddSignature(env.id, env.sig)
+	var node *hashgraph.OpNode
+	if env.op == nil {
+		node = hashgraph.NewNodeWithId(env.id, rep.crdt.DeliverCoinOp(env.coinOp), prev)
+	} else {
+		node = hashgraph.NewNodeWithId(env.id, rep.scenario.Bind(&rep.crdt, env.op), prev)
+		rep.Delivered = append(rep.Delivered, env.op.Name)
+	}
+	rep.nodes[env.id] = node
+	if prev == nil {
+		rep.root = node
+	}
+	rep.tips = append(lo.Without(rep.tips, env.prevIds...), env.id)
+	// Posts neither move points nor decide conflicts, so they leave nothing owed
+	rep.stale = rep.stale || env.op == nil || env.op.Kind != scenario.Post
+}
+
+// coinEnvelope wraps the payload of a deal or coin share the participant issues, after every operation it delivered.
+func (rep *Replica) coinEnvelope(payload []byte) *envelope {
+	prevIds := slices.Clone(rep.tips)
+	return &envelope{
+		coinOp:  payload,
+		id:      hashgraph.ContentId(payload, prevIds),
+		prevIds: prevIds,
+		sig:     accesscontrolapp.SignOp(rep.User.Key, payload, prevIds),
+	}
+}
+
+// owed returns the envelopes of the deals and coin shares the participant owes in the app, as it sees the group, and
+// has not issued yet.
+func (rep *Replica) owed(app *accesscontrolapp.App) []*envelope {
+	owed := make([]*envelope, 0)
+	for _, deal := range app.PendingDeals() {
+		if deal.Dealer != rep.User.Id || rep.dealt[deal] {
+			continue
+		}
+		rep.dealt[deal] = true
+		payload, err := rep.crdt.DealPayload(deal.Dealer, deal.Refresh, app)
+		if err != nil {
+			slog.Error("Error dealing values", "replica", rep.User.Alias, "err", err)
+			continue
+		}
+		owed = append(owed, rep.coinEnvelope(payload))
+	}
+	for _, share := range app.PendingCoinShares() {
+		if share.Owner != rep.User.Id || rep.revealed[share] {
+			continue
+		}
+		rep.revealed[share] = true
+		payload, err := rep.crdt.CoinSharesPayload(share.Owner, share.Seed, app)
+		if err != nil {
+			slog.Error("Error revealing coin shares", "replica", rep.User.Alias, "err", err)
+			continue
+		}
+		owed = append(owed, rep.coinEnvelope(payload))
+	}
+	return owed
+}
+
+// Execute runs the CRDT over the operations this replica has delivered so far.
+func (rep *Replica) Execute(seed int, numPoints, threshold int) (*accesscontrolapp.App, error) {
+	if rep.root != nil {
+		hashgraph.RunHashgraphCausal(seed, rep.root)
+	}
+	app, err := accesscontrolapp.ExecuteCRDT(&rep.crdt, numPoints, threshold)
+	rep.crdt.Clear()
+	return app, err
+}
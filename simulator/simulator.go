@@ -0,0 +1,188 @@
+// Package simulator replays a scenario across several replicas connected by an unreliable network.
+// Each participant of the scenario holds its own hashgraph and CRDT, and issues its operations once it has delivered
+// every operation they come after. Operation ids are derived from their content, so replicas can check them on receipt.
+// Participants also issue the deals and coin shares they owe as they see the group, after every operation they
+// delivered, so that the conflicts settled by a coin toss are decided.
+package simulator
+
+import (
+	"dare_randomized_access_control/accesscontrolapp"
+	"dare_randomized_access_control/hashgraph"
+	"dare_randomized_access_control/scenario"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"math/rand"
+)
+
+// Config parametrizes the simulated network and the application run by the replicas.
+// Delays and timeouts are measured in simulated ticks.
+type Config struct {
+	Seed              int64
+	MinDelay          int
+	MaxDelay          int
+	DropRate          float64
+	RetransmitTimeout int
+	NumPoints         int
+	Threshold         int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Seed:              0,
+		MinDelay:          1,
+		MaxDelay:          20,
+		DropRate:          0.1,
+		RetransmitTimeout: 30,
+		NumPoints:         1000,
+		Threshold:         2,
+	}
+}
+
+type Simulation struct {
+	scenario *scenario.Scenario
+	config   Config
+	r        *rand.Rand
+	net      *network
+	Replicas []*Replica
+	replicas map[string]*Replica
+	ids      map[string]uuid.UUID
+	unissued []*scenario.Op
+}
+
+// Result holds the state reached by every replica once all messages have been delivered.
+type Result struct {
+	Apps map[string]*accesscontrolapp.App
+	// Divergence describes how two replicas differ. Empty if all replicas converged.
+	Divergence string
+	Sent       int
+	Dropped    int
+	Ticks      int
+}
+
+func New(sc *scenario.Scenario, config Config) (*Simulation, error) {
+	if config.DropRate >= 1 {
+		return nil, fmt.Errorf("drop rate must be lower than 1 for messages to be eventually delivered, got %v", config.DropRate)
+	}
+	r := rand.New(rand.NewSource(config.Seed))
+	sim := &Simulation{
+		scenario: sc,
+		config:   config,
+		r:        r,
+		net:      newNetwork(r, config),
+		Replicas: make([]*Replica, 0, len(sc.Users)),
+		replicas: make(map[string]*Replica),
+		ids:      make(map[string]uuid.UUID),
+		unissued: append([]*scenario.Op{}, sc.Ops...),
+	}
+	for _, user := range sc.Users {
+		rep := newReplica(sc, user)
+		sim.Replicas = append(sim.Replicas, rep)
+		sim.replicas[user.Alias] = rep
+	}
+	return sim, nil
+}
+
+func (sim *Simulation) Replica(alias string) *Replica {
+	return sim.replicas[alias]
+}
+
+func (sim *Simulation) Run() (*Result, error) {
+	for {
+		for issued := true; issued; issued = sim.issueReady() {
+			if err := sim.issueOwed(); err != nil {
+				return nil, err
+			}
+		}
+		ev := sim.net.next()
+		if ev == nil {
+			break
+		}
+		ev.to.receive(ev.envelope)
+	}
+	if len(sim.unissued) > 0 {
+		return nil, fmt.Errorf("operation %s could never be issued", sim.unissued[0].Name)
+	}
+	return sim.collect()
+}
+
+// issueReady issues, at its issuer, the first operation whose previous operations have all been delivered there.
+// Returns whether an operation was issued.
+func (sim *Simulation) issueReady() bool {
+	for i, op := range sim.unissued {
+		rep := sim.replicas[op.Issuer]
+		if !lo.EveryBy(op.After, func(name string) bool { return rep.has(sim.ids[name]) }) {
+			continue
+		}
+		prevIds := lo.Map(op.After, func(name string, _ int) uuid.UUID { return sim.ids[name] })
+		payload := sim.scenario.Payload(op)
+		env := &envelope{
+			op:      op,
+			id:      hashgraph.ContentId(payload, prevIds),
+			prevIds: prevIds,
+			sig:     accesscontrolapp.SignOp(rep.User.Key, payload, prevIds),
+		}
+		sim.ids[op.Name] = env.id
+		sim.issue(rep, env)
+		sim.unissued = append(sim.unissued[:i], sim.unissued[i+1:]...)
+		return true
+	}
+	return false
+}
+
+// issueOwed issues, at their dealers and owners, the deals and coin shares owed in the group as they see it, until
+// none is owed. Participants issue them before their next operation of the scenario.
+func (sim *Simulation) issueOwed() error {
+	for issued := true; issued; {
+		issued = false
+		for _, rep := range sim.Replicas {
+			if !rep.stale {
+				continue
+			}
+			rep.stale = false
+			app, err := rep.Execute(int(sim.r.Int63()), sim.config.NumPoints, sim.config.Threshold)
+			if err != nil {
+				return fmt.Errorf("replica %s unable to execute CRDT: %v", rep.User.Alias, err)
+			}
+			for _, env := range rep.owed(app) {
+				rep.Issued = append(rep.Issued, env.id)
+				sim.issue(rep, env)
+				issued = true
+			}
+		}
+	}
+	return nil
+}
+
+// issue delivers the envelope at the replica issuing it and sends it to every other replica.
+func (sim *Simulation) issue(rep *Replica, env *envelope) {
+	rep.receive(env)
+	for _, other := range sim.Replicas {
+		if other != rep {
+			sim.net.send(env, other)
+		}
+	}
+}
+
+func (sim *Simulation) collect() (*Result, error) {
+	res := &Result{
+		Apps:    make(map[string]*accesscontrolapp.App),
+		Sent:    sim.net.sent,
+		Dropped: sim.net.dropped,
+		Ticks:   sim.net.now,
+	}
+	var reference *Replica
+	for _, rep := range sim.Replicas {
+		app, err := rep.Execute(int(sim.r.Int63()), sim.config.NumPoints, sim.config.Threshold)
+		if err != nil {
+			return nil, fmt.Errorf("replica %s unable to execute CRDT: %v", rep.User.Alias, err)
+		}
+		res.Apps[rep.User.Alias] = app
+		if reference == nil {
+			reference = rep
+		} else if diff := res.Apps[reference.User.Alias].Diff(app); diff != "" && res.Divergence == "" {
+			res.Divergence = fmt.Sprintf("replicas %s and %s diverge: %s", reference.User.Alias, rep.User.Alias, diff)
+		}
+	}
+	return res, nil
+}
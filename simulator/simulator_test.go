@@ -0,0 +1,171 @@
+package simulator
+
+import (
+	"dare_randomized_access_control/accesscontrolapp"
+	"dare_randomized_access_control/hashgraph"
+	"dare_randomized_access_control/scenario"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const forkScenario = `
+seed 0
+user alice Alice
+user bob Bob
+user claire Claire
+init start alice
+add addBob alice bob 0..30 after start
+add addClaire alice claire 30..60 after start
+post bobPost bob "Bob: hi" after addBob
+post clairePost claire "Claire: hi" after addClaire
+post alicePost alice "Alice: hi both" after bobPost clairePost
+rem remBob claire bob after alicePost
+`
+
+const conflictScenario = `
+seed 0
+user alice Alice
+user bob Bob
+user claire Claire
+init start alice
+add addBob alice bob 0..30 after start
+add addClaire alice claire 30..60 after addBob
+post alicePost alice "Alice: welcome both" after addClaire
+rem remBob claire bob after alicePost
+rem remClaire bob claire after alicePost
+`
+
+func TestShouldDeliverAllOperationsToAllReplicas(t *testing.T) {
+	accesscontrolapp.LogMembershipChanges = false
+	sc, err := scenario.Parse(strings.NewReader(forkScenario))
+	assert.NoError(t, err)
+	sim, err := New(sc, DefaultConfig())
+	assert.NoError(t, err)
+	res, err := sim.Run()
+	assert.NoError(t, err)
+	for _, rep := range sim.Replicas {
+		assert.ElementsMatch(t, lo.Map(sc.Ops, func(op *scenario.Op, _ int) string { return op.Name }), rep.Delivered)
+	}
+	issued := lo.SumBy(sim.Replicas, func(rep *Replica) int { return len(rep.Issued) })
+	assert.Greater(t, issued, 0)
+	assert.Equal(t, (len(sc.Ops)+issued)*(len(sc.Users)-1), res.Sent)
+}
+
+func TestShouldConvergeWithDropsAndReordering(t *testing.T) {
+	accesscontrolapp.LogMembershipChanges = false
+	sc, err := scenario.Load("../scenarios/demo.scenario")
+	assert.NoError(t, err)
+	for seed := int64(0); seed < 2; seed++ {
+		config := DefaultConfig()
+		config.Seed = seed
+		config.DropRate = 0.3
+		config.NumPoints = 600
+		sim, err := New(sc, config)
+		assert.NoError(t, err)
+		res, err := sim.Run()
+		assert.NoError(t, err)
+		assert.Empty(t, res.Divergence)
+		assert.Greater(t, res.Dropped, 0)
+		assert.Equal(t, len(sc.Users), len(res.Apps))
+		orders := lo.Map(sim.Replicas, func(rep *Replica, _ int) []string { return rep.Delivered })
+		assert.True(t, lo.SomeBy(orders[1:], func(o []string) bool { return !slices.Equal(orders[0], o) }))
+	}
+}
+
+func TestShouldSettleConflictWithCoinToss(t *testing.T) {
+	accesscontrolapp.LogMembershipChanges = false
+	sc, err := scenario.Parse(strings.NewReader(conflictScenario))
+	assert.NoError(t, err)
+	config := DefaultConfig()
+	config.NumPoints = 100
+	sim, err := New(sc, config)
+	assert.NoError(t, err)
+	res, err := sim.Run()
+	assert.NoError(t, err)
+	assert.Empty(t, res.Divergence)
+	// The participants deal to the refreshes and publish their values, so the coin toss removes one of the two
+	for _, app := range res.Apps {
+		assert.Equal(t, 2, len(app.Members()))
+		assert.Empty(t, app.PendingDeals())
+		assert.Empty(t, app.PendingCoinShares())
+	}
+}
+
+func TestShouldBeDeterministic(t *testing.T) {
+	accesscontrolapp.LogMembershipChanges = false
+	sc, err := scenario.Parse(strings.NewReader(forkScenario))
+	assert.NoError(t, err)
+	sim1, err := New(sc, DefaultConfig())
+	assert.NoError(t, err)
+	res1, err := sim1.Run()
+	assert.NoError(t, err)
+	sim2, err := New(sc, DefaultConfig())
+	assert.NoError(t, err)
+	res2, err := sim2.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, res1.Ticks, res2.Ticks)
+	assert.Equal(t, res1.Dropped, res2.Dropped)
+	for i := range sim1.Replicas {
+		assert.Equal(t, sim1.Replicas[i].Delivered, sim2.Replicas[i].Delivered)
+	}
+}
+
+func TestReplicaShouldBufferUntilCausallyReady(t *testing.T) {
+	sc, err := scenario.Parse(strings.NewReader(forkScenario))
+	assert.NoError(t, err)
+	rep := newReplica(sc, sc.User("claire"))
+	start := makeEnvelope(sc, "start")
+	add := makeEnvelope(sc, "addBob", start)
+	post := makeEnvelope(sc, "bobPost", add)
+	rep.receive(post)
+	rep.receive(add)
+	assert.Empty(t, rep.Delivered)
+	rep.receive(start)
+	assert.Equal(t, []string{"start", "addBob", "bobPost"}, rep.Delivered)
+	rep.receive(add)
+	assert.Equal(t, 3, len(rep.Delivered))
+	app, err := rep.Execute(0, 100, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(app.Members()))
+	assert.Equal(t, 1, len(app.Msgs))
+}
+
+func TestReplicaShouldRejectTamperedOperations(t *testing.T) {
+	sc, err := scenario.Parse(strings.NewReader(forkScenario))
+	assert.NoError(t, err)
+	rep := newReplica(sc, sc.User("claire"))
+	start := makeEnvelope(sc, "start")
+	rep.receive(start)
+	tampered := makeEnvelope(sc, "addBob", start)
+	tampered.op = sc.Op("addClaire")
+	rep.receive(tampered)
+	forged := makeEnvelope(sc, "addClaire", start)
+	forged.id = uuid.New()
+	rep.receive(forged)
+	assert.Equal(t, []string{"start"}, rep.Delivered)
+	assert.Equal(t, []uuid.UUID{tampered.id, forged.id}, rep.Rejected)
+}
+
+func makeEnvelope(sc *scenario.Scenario, name string, prev ...*envelope) *envelope {
+	op := sc.Op(name)
+	prevIds := lo.Map(prev, func(p *envelope, _ int) uuid.UUID { return p.id })
+	return &envelope{
+		op:      op,
+		id:      hashgraph.ContentId(sc.Payload(op), prevIds),
+		prevIds: prevIds,
+		sig:     accesscontrolapp.SignOp(sc.User(op.Issuer).Key, sc.Payload(op), prevIds),
+	}
+}
+
+func TestShouldRefuseDroppingEveryMessage(t *testing.T) {
+	sc, err := scenario.Parse(strings.NewReader(forkScenario))
+	assert.NoError(t, err)
+	config := DefaultConfig()
+	config.DropRate = 1
+	_, err = New(sc, config)
+	assert.Error(t, err)
+}